@@ -0,0 +1,155 @@
+package rsmt2d
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// AvailabilityMask is a bitset recording which cells of a square are
+// available, with the number of available cells per row and column.
+type AvailabilityMask struct {
+	width        uint
+	bits         []uint64
+	rowCounts    []uint
+	columnCounts []uint
+}
+
+// NewAvailabilityMask returns a mask of a square of width*width cells, none
+// of them available.
+func NewAvailabilityMask(width uint) *AvailabilityMask {
+	return &AvailabilityMask{
+		width:        width,
+		bits:         make([]uint64, (width*width+63)/64),
+		rowCounts:    make([]uint, width),
+		columnCounts: make([]uint, width),
+	}
+}
+
+// Width returns the width of the square.
+func (m *AvailabilityMask) Width() uint {
+	return m.width
+}
+
+// IsSet reports whether the cell is available. It panics if the cell is
+// outside of the square.
+func (m *AvailabilityMask) IsSet(row uint, column uint) bool {
+	i := m.index(row, column)
+	return m.bits[i/64]&(1<<(i%64)) != 0
+}
+
+// Set marks the cell as available. It panics if the cell is outside of the
+// square.
+func (m *AvailabilityMask) Set(row uint, column uint) {
+	if m.IsSet(row, column) {
+		return
+	}
+	i := row*m.width + column
+	m.bits[i/64] |= 1 << (i % 64)
+	m.rowCounts[row]++
+	m.columnCounts[column]++
+}
+
+// Clear marks the cell as missing. It panics if the cell is outside of the
+// square.
+func (m *AvailabilityMask) Clear(row uint, column uint) {
+	if !m.IsSet(row, column) {
+		return
+	}
+	i := row*m.width + column
+	m.bits[i/64] &^= 1 << (i % 64)
+	m.rowCounts[row]--
+	m.columnCounts[column]--
+}
+
+// index returns the bit of the cell, after checking that it is inside of the
+// square.
+func (m *AvailabilityMask) index(row uint, column uint) uint {
+	if row >= m.width || column >= m.width {
+		panic(fmt.Sprintf("rsmt2d: cell (%d, %d) out of range of mask of width %d", row, column, m.width))
+	}
+	return row*m.width + column
+}
+
+// SetRow marks all cells of the row as available.
+func (m *AvailabilityMask) SetRow(row uint) {
+	for column := uint(0); column < m.width; column++ {
+		m.Set(row, column)
+	}
+}
+
+// SetColumn marks all cells of the column as available.
+func (m *AvailabilityMask) SetColumn(column uint) {
+	for row := uint(0); row < m.width; row++ {
+		m.Set(row, column)
+	}
+}
+
+// RowCount returns the number of available cells in the row.
+func (m *AvailabilityMask) RowCount(row uint) uint {
+	return m.rowCounts[row]
+}
+
+// ColumnCount returns the number of available cells in the column.
+func (m *AvailabilityMask) ColumnCount(column uint) uint {
+	return m.columnCounts[column]
+}
+
+// IsRowComplete reports whether all cells of the row are available.
+func (m *AvailabilityMask) IsRowComplete(row uint) bool {
+	return m.rowCounts[row] == m.width
+}
+
+// IsColumnComplete reports whether all cells of the column are available.
+func (m *AvailabilityMask) IsColumnComplete(column uint) bool {
+	return m.columnCounts[column] == m.width
+}
+
+// Count returns the number of available cells.
+func (m *AvailabilityMask) Count() uint {
+	var count int
+	for _, word := range m.bits {
+		count += bits.OnesCount64(word)
+	}
+	return uint(count)
+}
+
+// IsComplete reports whether all cells are available.
+func (m *AvailabilityMask) IsComplete() bool {
+	return m.Count() == m.width*m.width
+}
+
+// Clone returns a copy of the mask.
+func (m *AvailabilityMask) Clone() *AvailabilityMask {
+	return &AvailabilityMask{
+		width:        m.width,
+		bits:         append([]uint64(nil), m.bits...),
+		rowCounts:    append([]uint(nil), m.rowCounts...),
+		columnCounts: append([]uint(nil), m.columnCounts...),
+	}
+}
+
+// without returns a mask of the cells available in m but not in other, which
+// must have the same width.
+func (m *AvailabilityMask) without(other *AvailabilityMask) *AvailabilityMask {
+	mask := NewAvailabilityMask(m.width)
+	for row := uint(0); row < m.width; row++ {
+		for column := uint(0); column < m.width; column++ {
+			if m.IsSet(row, column) && !other.IsSet(row, column) {
+				mask.Set(row, column)
+			}
+		}
+	}
+	return mask
+}
+
+// availabilityMaskOf returns the mask of the non-nil chunks of a flattened
+// square.
+func availabilityMaskOf(data [][]byte, width uint) *AvailabilityMask {
+	mask := NewAvailabilityMask(width)
+	for i, chunk := range data {
+		if chunk != nil {
+			mask.Set(uint(i)/width, uint(i)%width)
+		}
+	}
+	return mask
+}
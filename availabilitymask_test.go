@@ -0,0 +1,58 @@
+package rsmt2d
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAvailabilityMask(t *testing.T) {
+	mask := NewAvailabilityMask(10)
+	assert.Equal(t, uint(10), mask.Width())
+	assert.Equal(t, uint(0), mask.Count())
+
+	mask.Set(0, 0)
+	mask.Set(0, 0)
+	mask.Set(6, 4) // bit 64, the first of the second word
+	mask.Set(9, 9)
+	assert.True(t, mask.IsSet(0, 0))
+	assert.True(t, mask.IsSet(6, 4))
+	assert.False(t, mask.IsSet(6, 3))
+	assert.Equal(t, uint(3), mask.Count())
+	assert.Equal(t, uint(1), mask.RowCount(6))
+	assert.Equal(t, uint(1), mask.ColumnCount(4))
+
+	mask.SetRow(6)
+	assert.True(t, mask.IsRowComplete(6))
+	assert.False(t, mask.IsRowComplete(5))
+	assert.Equal(t, uint(1), mask.ColumnCount(3))
+	assert.Equal(t, uint(2), mask.ColumnCount(9))
+
+	clone := mask.Clone()
+	mask.Clear(6, 4)
+	mask.Clear(6, 4)
+	assert.False(t, mask.IsSet(6, 4))
+	assert.Equal(t, uint(9), mask.RowCount(6))
+	assert.Equal(t, uint(0), mask.ColumnCount(4))
+	assert.True(t, clone.IsSet(6, 4), "clones must not share state")
+	assert.Equal(t, uint(12), clone.Count())
+
+	for i := uint(0); i < 10; i++ {
+		mask.SetColumn(i)
+	}
+	assert.True(t, mask.IsComplete())
+	assert.True(t, mask.IsColumnComplete(4))
+	assert.False(t, clone.IsComplete())
+
+	assert.Panics(t, func() { mask.IsSet(0, 10) })
+	assert.Panics(t, func() { mask.Set(10, 0) })
+	assert.Panics(t, func() { mask.Clear(3, 12) })
+}
+
+func TestAvailabilityMaskOf(t *testing.T) {
+	mask := availabilityMaskOf([][]byte{{1}, nil, nil, {4}}, 2)
+	assert.True(t, mask.IsSet(0, 0))
+	assert.False(t, mask.IsSet(0, 1))
+	assert.False(t, mask.IsSet(1, 0))
+	assert.True(t, mask.IsSet(1, 1))
+}
@@ -0,0 +1,138 @@
+package rsmt2d
+
+import (
+	"container/list"
+	"sync"
+)
+
+// DefaultCacheSize is the number of distinct widths a codec caches encoding
+// state for, unless changed with SetCacheSize.
+const DefaultCacheSize = 32
+
+// CachingCodec is implemented by codecs that cache encoding state, such as
+// FEC matrices, per original data width.
+type CachingCodec interface {
+	Codec
+	// WarmCache precomputes the state for the given original data widths.
+	WarmCache(widths ...int) error
+	// SetCacheSize bounds the number of cached widths. The least recently
+	// used entries are evicted when the cache is full. A size of zero
+	// disables caching.
+	SetCacheSize(size int)
+	// CacheStats returns the current usage counters of the cache.
+	CacheStats() CacheStats
+}
+
+// CacheStats holds the usage counters of a codec cache.
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	// Len is the number of cached widths.
+	Len int
+	// Size is the maximum number of cached widths.
+	Size int
+}
+
+// lruCache is a goroutine-safe, bounded cache of values keyed by width, or by
+// any other comparable key. Values are created on demand by build and must not
+// be modified afterwards.
+type lruCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[interface{}]*list.Element
+	order   *list.List // front is most recently used
+	build   func(key interface{}) (interface{}, error)
+	stats   CacheStats
+}
+
+type lruEntry struct {
+	key   interface{}
+	value interface{}
+}
+
+func newLRUCache(size int, build func(key interface{}) (interface{}, error)) *lruCache {
+	return &lruCache{
+		size:    size,
+		entries: make(map[interface{}]*list.Element),
+		order:   list.New(),
+		build:   build,
+	}
+}
+
+// get returns the value for key, building and caching it on a miss.
+func (c *lruCache) get(key interface{}) (interface{}, error) {
+	c.mu.Lock()
+	if elem, ok := c.entries[key]; ok {
+		c.stats.Hits++
+		c.order.MoveToFront(elem)
+		c.mu.Unlock()
+		return elem.Value.(*lruEntry).value, nil
+	}
+	c.stats.Misses++
+	c.mu.Unlock()
+
+	// Build outside of the lock, so that a slow build does not block
+	// lookups of other keys.
+	value, err := c.build(key)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		// Another goroutine built it concurrently.
+		c.order.MoveToFront(elem)
+		return elem.Value.(*lruEntry).value, nil
+	}
+	if c.size > 0 {
+		c.entries[key] = c.order.PushFront(&lruEntry{key, value})
+		c.evict()
+	}
+
+	return value, nil
+}
+
+// warm builds and caches the values for the given widths.
+func (c *lruCache) warm(widths ...int) error {
+	for _, width := range widths {
+		if _, err := c.get(width); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *lruCache) setSize(size int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if size < 0 {
+		size = 0
+	}
+	c.size = size
+	c.evict()
+}
+
+// evict drops the least recently used entries until the cache fits its size.
+// The caller must hold c.mu.
+func (c *lruCache) evict() {
+	for c.order.Len() > c.size {
+		elem := c.order.Back()
+		c.order.Remove(elem)
+		delete(c.entries, elem.Value.(*lruEntry).key)
+		c.stats.Evictions++
+	}
+}
+
+func (c *lruCache) cacheStats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Len = c.order.Len()
+	stats.Size = c.size
+	return stats
+}
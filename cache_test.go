@@ -0,0 +1,95 @@
+package rsmt2d
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUCache(t *testing.T) {
+	builds := 0
+	cache := newLRUCache(2, func(key interface{}) (interface{}, error) {
+		width := key.(int)
+		builds++
+		if width < 0 {
+			return nil, errors.New("negative width")
+		}
+		return width * 10, nil
+	})
+
+	value, err := cache.get(1)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, value)
+	_, _ = cache.get(2)
+	_, _ = cache.get(1) // 1 is now the most recently used entry
+	_, _ = cache.get(3) // evicts 2
+	assert.Equal(t, CacheStats{Hits: 1, Misses: 3, Evictions: 1, Len: 2, Size: 2}, cache.cacheStats())
+
+	_, _ = cache.get(1)
+	_, _ = cache.get(2)
+	assert.Equal(t, 4, builds, "2 should have been evicted and rebuilt")
+
+	_, err = cache.get(-1)
+	assert.Error(t, err)
+	assert.Equal(t, 2, cache.cacheStats().Len, "failed builds must not be cached")
+
+	assert.NoError(t, cache.warm(4, 5))
+	builds = 0
+	_, _ = cache.get(4)
+	_, _ = cache.get(5)
+	assert.Equal(t, 0, builds, "warmed widths should be cached")
+
+	cache.setSize(0)
+	stats := cache.cacheStats()
+	assert.Equal(t, 0, stats.Len)
+	_, _ = cache.get(4)
+	assert.Equal(t, 1, builds, "a cache of size zero should not store anything")
+}
+
+func TestLRUCacheConcurrentUse(t *testing.T) {
+	cache := newLRUCache(4, func(key interface{}) (interface{}, error) {
+		return key, nil
+	})
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				value, err := cache.get((g + i) % 8)
+				if err != nil || value != (g+i)%8 {
+					t.Errorf("unexpected value %v, err %v", value, err)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	stats := cache.cacheStats()
+	assert.Equal(t, uint64(800), stats.Hits+stats.Misses)
+	assert.Equal(t, 4, stats.Len)
+}
+
+func TestCachingCodecs(t *testing.T) {
+	for _, ct := range []CodecType{RSGF8, RSGF16} {
+		codec, ok := GetCodec(ct)
+		if !ok {
+			t.Fatalf("codec %d not registered", ct)
+		}
+		caching, ok := codec.(CachingCodec)
+		if !ok {
+			t.Fatalf("codec %d does not implement CachingCodec", ct)
+		}
+
+		assert.NoError(t, caching.WarmCache(3))
+		before := caching.CacheStats()
+		_, err := caching.Encode([][]byte{{1, 2}, {3, 4}, {5, 6}})
+		assert.NoError(t, err)
+		after := caching.CacheStats()
+		assert.Equal(t, before.Hits+1, after.Hits, "codec %d: encoding a warmed width should hit the cache", ct)
+		assert.Equal(t, before.Misses, after.Misses)
+	}
+}
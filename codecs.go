@@ -3,6 +3,7 @@ package rsmt2d
 import (
 	"errors"
 	"fmt"
+	"sync"
 )
 
 // codecType type
@@ -14,37 +15,86 @@ const (
 	RSGF8       CodecType = iota
 	LeopardFF8  CodecType = 1
 	LeopardFF16 CodecType = 2
+	// RSGF16 represents Reed-Solomon codecType with a 16-bit Finite Galois Field (2^16).
+	// It takes time quadratic in the width of the square, so LeopardFF16 is
+	// the codec to use for squares wider than a few dozen chunks.
+	RSGF16 CodecType = 3
 )
 
+// FirstCustomCodecType is the first id handed out by NewCodecType.
+// Ids below it are reserved for the codecs shipped with this package.
+const FirstCustomCodecType CodecType = 256
+
+// Codec is an erasure code that can be used to extend and repair data squares.
+// A registered codec is shared by all squares, so implementations must be
+// safe for concurrent use.
 type Codec interface {
-	encode(data [][]byte) ([][]byte, error)
-	decode(data [][]byte) ([][]byte, error)
-	codecType() CodecType
-	// maxChunks returns the max. number of chunks each code supports in a 2D square.
-	maxChunks() int
+	// Encode returns len(data) parity chunks for the given data chunks.
+	Encode(data [][]byte) ([][]byte, error)
+	// Decode takes 2*k chunks, data followed by parity, with missing chunks
+	// represented as nil, and returns the k original data chunks.
+	Decode(data [][]byte) ([][]byte, error)
+	// CodecType returns the id the codec is registered under.
+	CodecType() CodecType
+	// MaxChunks returns the max. number of chunks each code supports in a 2D square.
+	MaxChunks() int
+}
+
+var (
+	codecsMu          sync.RWMutex
+	codecs            = make(map[CodecType]Codec)
+	nextCustomCodecID = FirstCustomCodecType
+)
+
+// NewCodecType claims a new CodecType id that does not collide with the
+// built-in codecs or any id previously returned by NewCodecType.
+func NewCodecType() CodecType {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+
+	ct := nextCustomCodecID
+	nextCustomCodecID++
+	return ct
 }
 
-var codecs = make(map[CodecType]Codec)
+// RegisterCodec makes a codec available under the given CodecType, so that it
+// can be used with ComputeExtendedDataSquare, ImportExtendedDataSquare and
+// RepairExtendedDataSquare. It panics if the id is already taken or does not
+// match codec.CodecType().
+func RegisterCodec(ct CodecType, codec Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
 
-func registerCodec(ct CodecType, codec Codec) {
+	if codec.CodecType() != ct {
+		panic(fmt.Sprintf("codec reports type %d, registered as %d", codec.CodecType(), ct))
+	}
 	if codecs[ct] != nil {
 		panic(fmt.Sprintf("%v already registered", codec))
 	}
 	codecs[ct] = codec
 }
 
+// GetCodec returns the codec registered under the given CodecType.
+func GetCodec(ct CodecType) (Codec, bool) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+
+	codec, ok := codecs[ct]
+	return codec, ok
+}
+
 func Encode(data [][]byte, codec CodecType) ([][]byte, error) {
-	if codec, ok := codecs[codec]; !ok {
+	if codec, ok := GetCodec(codec); !ok {
 		return nil, errors.New("invalid codec")
 	} else {
-		return codec.encode(data)
+		return codec.Encode(data)
 	}
 }
 
 func Decode(data [][]byte, codec CodecType) ([][]byte, error) {
-	if codec, ok := codecs[codec]; !ok {
+	if codec, ok := GetCodec(codec); !ok {
 		return nil, errors.New("invalid codec")
 	} else {
-		return codec.decode(data)
+		return codec.Decode(data)
 	}
 }
@@ -0,0 +1,121 @@
+package rsmt2d
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// wrappedCodec is a third-party codec built on top of the RSGF8 implementation.
+type wrappedCodec struct {
+	*rsGF8Codec
+	ct CodecType
+}
+
+func (c *wrappedCodec) CodecType() CodecType {
+	return c.ct
+}
+
+// registerTestCodec registers codec under ct until the end of the test, so that
+// tests iterating the registered codecs do not depend on the order tests run
+// in.
+func registerTestCodec(t *testing.T, ct CodecType, codec Codec) {
+	RegisterCodec(ct, codec)
+	t.Cleanup(func() {
+		codecsMu.Lock()
+		defer codecsMu.Unlock()
+		delete(codecs, ct)
+	})
+}
+
+func TestRegisterCustomCodec(t *testing.T) {
+	ct := NewCodecType()
+	if ct < FirstCustomCodecType {
+		t.Fatalf("NewCodecType returned reserved id %d", ct)
+	}
+	if next := NewCodecType(); next == ct {
+		t.Fatalf("NewCodecType returned %d twice", ct)
+	}
+
+	registerTestCodec(t, ct, &wrappedCodec{newRSGF8Codec(), ct})
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("registering a codec twice did not panic")
+			}
+		}()
+		RegisterCodec(ct, &wrappedCodec{newRSGF8Codec(), ct})
+	}()
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("registering a codec under a mismatched id did not panic")
+			}
+		}()
+		RegisterCodec(NewCodecType(), &wrappedCodec{newRSGF8Codec(), ct})
+	}()
+
+	chunk := bytes.Repeat([]byte{1}, 64)
+	original, err := ComputeExtendedDataSquare([][]byte{chunk, chunk, chunk, chunk}, ct)
+	if err != nil {
+		t.Fatalf("ComputeExtendedDataSquare with custom codec failed: %v", err)
+	}
+
+	flattened := original.flattened()
+	flattened[0], flattened[1], flattened[4] = nil, nil, nil
+	result, err := RepairExtendedDataSquare(original.RowRoots(), original.ColumnRoots(), flattened, ct)
+	if err != nil {
+		t.Fatalf("RepairExtendedDataSquare with custom codec failed: %v", err)
+	}
+	if !bytes.Equal(result.Cell(0, 0), chunk) {
+		t.Errorf("custom codec did not repair the square")
+	}
+}
+
+func TestCodecsConcurrentUse(t *testing.T) {
+	for codec := range codecs {
+		codec := codec
+		t.Run(fmt.Sprintf("codec %d", codec), func(t *testing.T) {
+			var wg sync.WaitGroup
+			for g := 0; g < 16; g++ {
+				wg.Add(1)
+				go func(g int) {
+					defer wg.Done()
+
+					width := 1 << uint(g%4)
+					data := make([][]byte, width*width)
+					for i := range data {
+						data[i] = bytes.Repeat([]byte{byte(g + i)}, 64)
+					}
+					original, err := ComputeExtendedDataSquare(data, codec)
+					if err != nil {
+						t.Errorf("ComputeExtendedDataSquare failed: %v", err)
+						return
+					}
+
+					// Drop the original quarter of the square.
+					flattened := original.flattened()
+					for i := 0; i < width; i++ {
+						for j := 0; j < width; j++ {
+							flattened[i*2*width+j] = nil
+						}
+					}
+					result, err := RepairExtendedDataSquare(original.RowRoots(), original.ColumnRoots(), flattened, codec)
+					if err != nil {
+						t.Errorf("RepairExtendedDataSquare failed: %v", err)
+						return
+					}
+					for i := range data {
+						if !bytes.Equal(result.Cell(uint(i/width), uint(i%width)), data[i]) {
+							t.Errorf("chunk %d not repaired", i)
+						}
+					}
+				}(g)
+			}
+			wg.Wait()
+		})
+	}
+}
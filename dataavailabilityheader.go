@@ -0,0 +1,150 @@
+package rsmt2d
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash"
+
+	"github.com/NebulousLabs/merkletree"
+)
+
+// DataAvailabilityHeader commits to all row and column roots of an extended
+// data square.
+type DataAvailabilityHeader struct {
+	RowRoots    [][]byte
+	ColumnRoots [][]byte
+}
+
+// NewDataAvailabilityHeader returns the header of an extended data square.
+func NewDataAvailabilityHeader(eds *ExtendedDataSquare) *DataAvailabilityHeader {
+	return &DataAvailabilityHeader{
+		RowRoots:    copyRoots(eds.RowRoots()),
+		ColumnRoots: copyRoots(eds.ColumnRoots()),
+	}
+}
+
+// Hash returns the data root, the Merkle root of the row roots followed by the
+// column roots. It is computed by the default tree with a hasher created by
+// newHasher, which should be the one the square was configured with, see
+// WithHasher.
+func (dah *DataAvailabilityHeader) Hash(newHasher func() hash.Hash) []byte {
+	return dah.tree(newHasher).Root()
+}
+
+// ValidateBasic checks that the header has as many row as column roots, an
+// even non-zero width, and roots of equal length.
+func (dah *DataAvailabilityHeader) ValidateBasic() error {
+	if len(dah.RowRoots) != len(dah.ColumnRoots) {
+		return fmt.Errorf("number of row roots %d does not match number of column roots %d", len(dah.RowRoots), len(dah.ColumnRoots))
+	}
+	if len(dah.RowRoots) == 0 || len(dah.RowRoots)%2 != 0 {
+		return fmt.Errorf("square width %d must be even and non-zero", len(dah.RowRoots))
+	}
+
+	rootSize := len(dah.RowRoots[0])
+	if rootSize == 0 {
+		return errors.New("roots must not be empty")
+	}
+	for i := range dah.RowRoots {
+		if len(dah.RowRoots[i]) != rootSize || len(dah.ColumnRoots[i]) != rootSize {
+			return fmt.Errorf("roots must all be %d bytes long", rootSize)
+		}
+	}
+
+	return nil
+}
+
+// Equals reports whether two headers hold the same roots.
+func (dah *DataAvailabilityHeader) Equals(other *DataAvailabilityHeader) bool {
+	return rootsEqual(dah.RowRoots, other.RowRoots) && rootsEqual(dah.ColumnRoots, other.ColumnRoots)
+}
+
+// RootProof is a Merkle inclusion proof of a row or column root in a data
+// root.
+type RootProof struct {
+	Root []byte
+	Axis Axis
+	// Index is the index of the row or column.
+	Index uint
+	// Siblings are the hashes of the sibling subtrees on the path from the
+	// root to the data root, bottom up.
+	Siblings [][]byte
+	// NumLeaves is the number of roots in the header, twice the square width.
+	NumLeaves uint
+}
+
+// ProveRoot returns a proof of inclusion of a row or column root in the data
+// root computed by Hash with newHasher.
+func (dah *DataAvailabilityHeader) ProveRoot(axis Axis, index uint, newHasher func() hash.Hash) (*RootProof, error) {
+	if index >= uint(len(dah.RowRoots)) || index >= uint(len(dah.ColumnRoots)) {
+		return nil, errors.New("index out of range")
+	}
+
+	leafIndex := index
+	root := dah.RowRoots[index]
+	if axis == ColumnAxis {
+		leafIndex += uint(len(dah.RowRoots))
+		root = dah.ColumnRoots[index]
+	}
+
+	_, proofSet, numLeaves, err := dah.tree(newHasher).Prove(leafIndex)
+	if err != nil {
+		return nil, err
+	}
+	return &RootProof{
+		Root:      root,
+		Axis:      axis,
+		Index:     index,
+		Siblings:  proofSet[1:],
+		NumLeaves: numLeaves,
+	}, nil
+}
+
+// VerifyRootProof checks a proof of a row or column root against a data root
+// computed by Hash with newHasher.
+func VerifyRootProof(dataRoot []byte, proof *RootProof, newHasher func() hash.Hash) bool {
+	if proof == nil || proof.NumLeaves%2 != 0 || proof.Index >= proof.NumLeaves/2 {
+		return false
+	}
+
+	leafIndex := proof.Index
+	if proof.Axis == ColumnAxis {
+		leafIndex += proof.NumLeaves / 2
+	}
+	proofSet := make([][]byte, 0, len(proof.Siblings)+1)
+	proofSet = append(proofSet, proof.Root)
+	proofSet = append(proofSet, proof.Siblings...)
+	return merkletree.VerifyProof(newHasher(), dataRoot, proofSet, uint64(leafIndex), uint64(proof.NumLeaves))
+}
+
+func (dah *DataAvailabilityHeader) tree(newHasher func() hash.Hash) Tree {
+	tree := NewDefaultTree(newHasher)
+	for _, root := range dah.RowRoots {
+		tree.Push(root)
+	}
+	for _, root := range dah.ColumnRoots {
+		tree.Push(root)
+	}
+	return tree
+}
+
+func copyRoots(roots [][]byte) [][]byte {
+	copied := make([][]byte, len(roots))
+	for i, root := range roots {
+		copied[i] = append([]byte(nil), root...)
+	}
+	return copied
+}
+
+func rootsEqual(a, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,109 @@
+package rsmt2d
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"testing"
+
+	"github.com/NebulousLabs/merkletree"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDataAvailabilityHeader(t *testing.T) {
+	eds, err := ComputeExtendedDataSquare([][]byte{
+		{1, 2}, {3, 4}, {5, 6},
+		{7, 8}, {9, 10}, {11, 12},
+		{13, 14}, {15, 16}, {17, 18},
+	}, RSGF8)
+	if err != nil {
+		panic(err)
+	}
+
+	dah := NewDataAvailabilityHeader(eds)
+	assert.NoError(t, dah.ValidateBasic())
+	assert.Equal(t, eds.RowRoots(), dah.RowRoots)
+	assert.Equal(t, eds.ColumnRoots(), dah.ColumnRoots)
+
+	expected := merkletree.New(sha256.New())
+	for _, root := range append(eds.RowRoots(), eds.ColumnRoots()...) {
+		expected.Push(root)
+	}
+	assert.Equal(t, expected.Root(), dah.Hash(sha256.New))
+
+	other := NewDataAvailabilityHeader(eds)
+	assert.True(t, dah.Equals(other))
+	other.ColumnRoots[5][0]++
+	assert.False(t, dah.Equals(other))
+	assert.NotEqual(t, dah.Hash(sha256.New), other.Hash(sha256.New))
+	assert.NotEqual(t, other.ColumnRoots[5], eds.ColumnRoots()[5], "header roots must not alias the square")
+}
+
+func TestDataAvailabilityHeaderValidateBasic(t *testing.T) {
+	root := make([]byte, 32)
+	tests := []struct {
+		name string
+		dah  DataAvailabilityHeader
+	}{
+		{"empty", DataAvailabilityHeader{}},
+		{"unequal counts", DataAvailabilityHeader{[][]byte{root, root}, [][]byte{root, root, root, root}}},
+		{"odd width", DataAvailabilityHeader{[][]byte{root}, [][]byte{root}}},
+		{"empty roots", DataAvailabilityHeader{[][]byte{{}, {}}, [][]byte{{}, {}}}},
+		{"unequal lengths", DataAvailabilityHeader{[][]byte{root, root}, [][]byte{root, root[:31]}}},
+	}
+	for _, tt := range tests {
+		assert.Error(t, tt.dah.ValidateBasic(), tt.name)
+	}
+}
+
+func TestRootProofs(t *testing.T) {
+	eds, err := ComputeExtendedDataSquare([][]byte{{1}, {2}, {3}, {4}}, RSGF8)
+	if err != nil {
+		panic(err)
+	}
+	dah := NewDataAvailabilityHeader(eds)
+	dataRoot := dah.Hash(sha256.New)
+
+	for i := uint(0); i < eds.Width(); i++ {
+		proof, err := dah.ProveRoot(RowAxis, i, sha256.New)
+		assert.NoError(t, err)
+		assert.Equal(t, eds.RowRoots()[i], proof.Root)
+		assert.True(t, VerifyRootProof(dataRoot, proof, sha256.New))
+
+		proof, err = dah.ProveRoot(ColumnAxis, i, sha256.New)
+		assert.NoError(t, err)
+		assert.Equal(t, eds.ColumnRoots()[i], proof.Root)
+		assert.True(t, VerifyRootProof(dataRoot, proof, sha256.New))
+	}
+
+	proof, err := dah.ProveRoot(RowAxis, 1, sha256.New)
+	assert.NoError(t, err)
+	proof.Axis = ColumnAxis
+	assert.False(t, VerifyRootProof(dataRoot, proof, sha256.New), "row root must not verify as column root")
+	proof.Axis = RowAxis
+	proof.Root = eds.RowRoots()[2]
+	assert.False(t, VerifyRootProof(dataRoot, proof, sha256.New))
+	assert.False(t, VerifyRootProof(dataRoot, nil, sha256.New))
+
+	_, err = dah.ProveRoot(RowAxis, 4, sha256.New)
+	assert.Error(t, err)
+}
+
+func TestDataAvailabilityHeaderHasher(t *testing.T) {
+	eds, err := ComputeExtendedDataSquare([][]byte{{1}, {2}, {3}, {4}}, RSGF8, WithHasher(sha512.New))
+	if err != nil {
+		panic(err)
+	}
+	dah := NewDataAvailabilityHeader(eds)
+	dataRoot := dah.Hash(sha512.New)
+
+	expected := merkletree.New(sha512.New())
+	for _, root := range append(eds.RowRoots(), eds.ColumnRoots()...) {
+		expected.Push(root)
+	}
+	assert.Equal(t, expected.Root(), dataRoot)
+
+	proof, err := dah.ProveRoot(ColumnAxis, 3, sha512.New)
+	assert.NoError(t, err)
+	assert.True(t, VerifyRootProof(dataRoot, proof, sha512.New))
+	assert.False(t, VerifyRootProof(dataRoot, proof, sha256.New))
+}
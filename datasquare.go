@@ -1,52 +1,115 @@
 package rsmt2d
 
 import (
-	"crypto/sha256"
+	"bytes"
 	"errors"
 	"hash"
 	"math"
-
-	"github.com/NebulousLabs/merkletree"
+	"sync"
 )
 
 type dataSquare struct {
-	square      [][][]byte
-	width       uint
-	chunkSize   uint
+	// buf holds all chunks contiguously, row by row. The cells of square are
+	// views into it.
+	buf       []byte
+	square    [][][]byte
+	width     uint
+	chunkSize uint
+	// rootsMu guards the lazy computation of the roots and trees, so that a
+	// square can be read from multiple goroutines.
+	rootsMu     sync.Mutex
 	rowRoots    [][]byte
 	columnRoots [][]byte
-	hasher      hash.Hash
+	// rowTrees and columnTrees are the trees the roots were computed with,
+	// kept for serving proofs. Entries of dirty rows and columns, and of trees
+	// not built yet for loaded roots, are nil.
+	rowTrees    []Tree
+	columnTrees []Tree
+	// dirtyRows and dirtyColumns list the rows and columns whose roots must
+	// be recomputed.
+	dirtyRows    []uint
+	dirtyColumns []uint
+	// rootsLoaded is set when the roots were not computed from the square,
+	// in which case the trees are built on demand.
+	rootsLoaded bool
+	// pooled is set when buf was taken from the configured buffer pool.
+	pooled bool
+	config config
 }
 
 func newDataSquare(data [][]byte) (*dataSquare, error) {
+	return newConfiguredDataSquare(data, newConfig(nil))
+}
+
+// newConfiguredDataSquare is newDataSquare with a configuration, whose buffer
+// pool provides the buffer of the square.
+func newConfiguredDataSquare(data [][]byte, cfg config) (*dataSquare, error) {
 	width := int(math.Ceil(math.Sqrt(float64(len(data)))))
 	if int(math.Pow(float64(width), 2)) != len(data) {
 		return nil, errors.New("number of chunks must be a square number")
 	}
 
-	square := make([][][]byte, width)
 	chunkSize := len(data[0])
-	for i := 0; i < width; i++ {
-		square[i] = data[i*width : i*width+width]
-
-		for j := 0; j < width; j++ {
-			if len(square[i][j]) != chunkSize {
-				return nil, errors.New("all chunks must be of equal size")
-			}
+	for _, chunk := range data {
+		if len(chunk) != chunkSize {
+			return nil, errors.New("all chunks must be of equal size")
 		}
 	}
 
-	return &dataSquare{
-		square:    square,
-		width:     uint(width),
-		chunkSize: uint(chunkSize),
-		hasher:    sha256.New(),
-	}, nil
+	buf := cfg.getBuffer(len(data) * chunkSize)
+	for i, chunk := range data {
+		copy(buf[i*chunkSize:], chunk)
+	}
+
+	ds := newDataSquareFromBuffer(buf, uint(width), uint(chunkSize), cfg)
+	ds.pooled = cfg.bufferPool != nil
+	return ds, nil
 }
 
-// SetHasher sets the hasher used for computing Merkle roots.
-func (ds *dataSquare) SetHasher(hasher hash.Hash) {
-	ds.hasher = hasher
+// newDataSquareFromBuffer returns a square of width*width chunks of chunkSize
+// bytes, backed by buf.
+func newDataSquareFromBuffer(buf []byte, width uint, chunkSize uint, cfg config) *dataSquare {
+	ds := &dataSquare{
+		buf:       buf,
+		width:     width,
+		chunkSize: chunkSize,
+		config:    cfg,
+	}
+	ds.square = squareViews(buf, width, chunkSize)
+	return ds
+}
+
+// squareViews slices buf into width*width cells, allocating a single slice
+// header per cell. Cells are capped so that appending to one cannot overwrite
+// the next.
+func squareViews(buf []byte, width uint, chunkSize uint) [][][]byte {
+	cells := make([][]byte, width*width)
+	for i := range cells {
+		start := uint(i) * chunkSize
+		cells[i] = buf[start : start+chunkSize : start+chunkSize]
+	}
+
+	square := make([][][]byte, width)
+	for i := uint(0); i < width; i++ {
+		square[i] = cells[i*width : (i+1)*width : (i+1)*width]
+	}
+	return square
+}
+
+// SetHasher sets the constructor of the hasher used for computing Merkle roots
+// and proofs. It is called once for every tree, so it must return a new hasher
+// on every call, and concurrently, so it must be safe for concurrent use. The
+// hasher is only used by the default tree.
+func (ds *dataSquare) SetHasher(newHasher func() hash.Hash) {
+	ds.config.newHasher = newHasher
+	ds.resetRoots()
+}
+
+// SetTree sets the constructor of the trees used for computing Merkle roots
+// and proofs. It must be safe for concurrent use, see TreeConstructorFn.
+func (ds *dataSquare) SetTree(newTree TreeConstructorFn) {
+	ds.config.newTree = newTree
+	ds.resetRoots()
 }
 
 func (ds *dataSquare) extendSquare(extendedWidth uint, fillerChunk []byte) error {
@@ -55,31 +118,24 @@ func (ds *dataSquare) extendSquare(extendedWidth uint, fillerChunk []byte) error
 	}
 
 	newWidth := ds.width + extendedWidth
-	newSquare := make([][][]byte, newWidth)
-
-	fillerExtendedRow := make([][]byte, extendedWidth)
-	for i := uint(0); i < extendedWidth; i++ {
-		fillerExtendedRow[i] = fillerChunk
-	}
+	newBuf := ds.config.getBuffer(int(newWidth * newWidth * ds.chunkSize))
+	newSquare := squareViews(newBuf, newWidth, ds.chunkSize)
 
-	fillerRow := make([][]byte, newWidth)
 	for i := uint(0); i < newWidth; i++ {
-		fillerRow[i] = fillerChunk
-	}
-
-	row := make([][]byte, ds.width)
-	for i := uint(0); i < ds.width; i++ {
-		copy(row, ds.square[i])
-		newSquare[i] = append(row, fillerExtendedRow...)
-	}
-
-	for i := ds.width; i < newWidth; i++ {
-		newSquare[i] = make([][]byte, newWidth)
-		copy(newSquare[i], fillerRow)
+		for j := uint(0); j < newWidth; j++ {
+			if i < ds.width && j < ds.width {
+				copy(newSquare[i][j], ds.square[i][j])
+			} else {
+				copy(newSquare[i][j], fillerChunk)
+			}
+		}
 	}
 
+	ds.release()
+	ds.buf = newBuf
 	ds.square = newSquare
 	ds.width = newWidth
+	ds.pooled = ds.config.bufferPool != nil
 
 	ds.resetRoots()
 
@@ -96,6 +152,20 @@ func (ds *dataSquare) Row(x uint) [][]byte {
 }
 
 func (ds *dataSquare) setRowSlice(x uint, y uint, newRow [][]byte) error {
+	if err := ds.writeRowSlice(x, y, newRow); err != nil {
+		return err
+	}
+
+	for i := uint(0); i < uint(len(newRow)); i++ {
+		ds.invalidateCell(x, y+i)
+	}
+
+	return nil
+}
+
+// writeRowSlice is setRowSlice without resetting the roots. Different rows can
+// be written concurrently.
+func (ds *dataSquare) writeRowSlice(x uint, y uint, newRow [][]byte) error {
 	for i := uint(0); i < uint(len(newRow)); i++ {
 		if len(newRow[i]) != int(ds.chunkSize) {
 			return errors.New("invalid chunk size")
@@ -103,11 +173,9 @@ func (ds *dataSquare) setRowSlice(x uint, y uint, newRow [][]byte) error {
 	}
 
 	for i := uint(0); i < uint(len(newRow)); i++ {
-		ds.square[x][y+i] = newRow[i]
+		copy(ds.square[x][y+i], newRow[i])
 	}
 
-	ds.resetRoots()
-
 	return nil
 }
 
@@ -126,6 +194,20 @@ func (ds *dataSquare) Column(y uint) [][]byte {
 }
 
 func (ds *dataSquare) setColumnSlice(x uint, y uint, newColumn [][]byte) error {
+	if err := ds.writeColumnSlice(x, y, newColumn); err != nil {
+		return err
+	}
+
+	for i := uint(0); i < uint(len(newColumn)); i++ {
+		ds.invalidateCell(x+i, y)
+	}
+
+	return nil
+}
+
+// writeColumnSlice is setColumnSlice without resetting the roots. Different
+// columns can be written concurrently.
+func (ds *dataSquare) writeColumnSlice(x uint, y uint, newColumn [][]byte) error {
 	for i := uint(0); i < uint(len(newColumn)); i++ {
 		if len(newColumn[i]) != int(ds.chunkSize) {
 			return errors.New("invalid chunk size")
@@ -133,47 +215,109 @@ func (ds *dataSquare) setColumnSlice(x uint, y uint, newColumn [][]byte) error {
 	}
 
 	for i := uint(0); i < uint(len(newColumn)); i++ {
-		ds.square[x+i][y] = newColumn[i]
+		copy(ds.square[x+i][y], newColumn[i])
 	}
 
-	ds.resetRoots()
-
 	return nil
 }
 
 func (ds *dataSquare) resetRoots() {
 	ds.rowRoots = nil
 	ds.columnRoots = nil
+	ds.rowTrees = nil
+	ds.columnTrees = nil
+	ds.dirtyRows = nil
+	ds.dirtyColumns = nil
+	ds.rootsLoaded = false
 }
 
+// loadRoots sets roots that were computed elsewhere, for instance stored
+// along with the square, so that they are not recomputed.
+func (ds *dataSquare) loadRoots(rowRoots [][]byte, columnRoots [][]byte) {
+	ds.resetRoots()
+	ds.rowRoots = rowRoots
+	ds.columnRoots = columnRoots
+	ds.rowTrees = make([]Tree, ds.width)
+	ds.columnTrees = make([]Tree, ds.width)
+	ds.rootsLoaded = true
+}
+
+// invalidateCell marks the row and column of a changed cell as dirty, so that
+// only their roots and trees are recomputed.
+func (ds *dataSquare) invalidateCell(x uint, y uint) {
+	if ds.rowRoots == nil {
+		// All roots will be recomputed anyway.
+		return
+	}
+	if ds.rootsLoaded {
+		// Trees that were not built yet cannot mark their row or column.
+		ds.resetRoots()
+		return
+	}
+	if ds.rowTrees[x] != nil {
+		ds.rowTrees[x] = nil
+		ds.dirtyRows = append(ds.dirtyRows, x)
+	}
+	if ds.columnTrees[y] != nil {
+		ds.columnTrees[y] = nil
+		ds.dirtyColumns = append(ds.dirtyColumns, y)
+	}
+}
+
+// rootsStale reports whether any root needs to be computed.
+func (ds *dataSquare) rootsStale() bool {
+	return ds.rowRoots == nil || len(ds.dirtyRows) > 0 || len(ds.dirtyColumns) > 0
+}
+
+// computeRoots computes the roots and trees of the dirty rows and columns, or
+// of all of them after a reset.
 func (ds *dataSquare) computeRoots() {
-	rowRoots := make([][]byte, ds.width)
-	columnRoots := make([][]byte, ds.width)
-	var rowTree *merkletree.Tree
-	var columnTree *merkletree.Tree
-	var rowData [][]byte
-	var columnData [][]byte
-	for i := uint(0); i < ds.width; i++ {
-		rowTree = merkletree.New(ds.hasher)
-		columnTree = merkletree.New(ds.hasher)
-		rowData = ds.Row(i)
-		columnData = ds.Column(i)
-		for j := uint(0); j < ds.width; j++ {
-			rowTree.Push(rowData[j])
-			columnTree.Push(columnData[j])
+	var rowRoots, columnRoots [][]byte
+	dirtyRows, dirtyColumns := ds.dirtyRows, ds.dirtyColumns
+	if ds.rowRoots == nil {
+		rowRoots = make([][]byte, ds.width)
+		columnRoots = make([][]byte, ds.width)
+		ds.rowTrees = make([]Tree, ds.width)
+		ds.columnTrees = make([]Tree, ds.width)
+		dirtyRows = make([]uint, ds.width)
+		for i := range dirtyRows {
+			dirtyRows[i] = uint(i)
 		}
-
-		rowRoots[i] = rowTree.Root()
-		columnRoots[i] = columnTree.Root()
+		dirtyColumns = dirtyRows
+	} else {
+		// Roots returned earlier must not change.
+		rowRoots = append([][]byte(nil), ds.rowRoots...)
+		columnRoots = append([][]byte(nil), ds.columnRoots...)
 	}
 
+	numDirtyRows := uint(len(dirtyRows))
+	_ = parallelFor(ds.config.parallelism, numDirtyRows+uint(len(dirtyColumns)), func(i uint) error {
+		axis, roots, trees := RowAxis, rowRoots, ds.rowTrees
+		if i < numDirtyRows {
+			i = dirtyRows[i]
+		} else {
+			i = dirtyColumns[i-numDirtyRows]
+			axis, roots, trees = ColumnAxis, columnRoots, ds.columnTrees
+		}
+
+		tree := ds.buildTree(axis, i)
+		roots[i] = tree.Root()
+		trees[i] = tree
+		return nil
+	})
+
 	ds.rowRoots = rowRoots
 	ds.columnRoots = columnRoots
+	ds.dirtyRows = nil
+	ds.dirtyColumns = nil
 }
 
 // RowRoots returns the Merkle roots of all the rows in the square.
 func (ds *dataSquare) RowRoots() [][]byte {
-	if ds.rowRoots == nil {
+	ds.rootsMu.Lock()
+	defer ds.rootsMu.Unlock()
+
+	if ds.rootsStale() {
 		ds.computeRoots()
 	}
 
@@ -182,43 +326,97 @@ func (ds *dataSquare) RowRoots() [][]byte {
 
 // ColumnRoots returns the Merkle roots of all the columns in the square.
 func (ds *dataSquare) ColumnRoots() [][]byte {
-	if ds.columnRoots == nil {
+	ds.rootsMu.Lock()
+	defer ds.rootsMu.Unlock()
+
+	if ds.rootsStale() {
 		ds.computeRoots()
 	}
 
 	return ds.columnRoots
 }
 
+// tree returns the tree of the row or column at index, computing the roots
+// and trees if it is not cached. Trees built for loaded roots must match
+// them, otherwise a RootMismatchError is returned.
+func (ds *dataSquare) tree(axis Axis, index uint) (Tree, error) {
+	ds.rootsMu.Lock()
+	defer ds.rootsMu.Unlock()
+
+	if ds.rootsStale() {
+		ds.computeRoots()
+	}
+
+	trees, roots := ds.rowTrees, ds.rowRoots
+	if axis == ColumnAxis {
+		trees, roots = ds.columnTrees, ds.columnRoots
+	}
+	if trees[index] == nil {
+		// Only the case for loaded roots.
+		tree := ds.buildTree(axis, index)
+		if !bytes.Equal(tree.Root(), roots[index]) {
+			return nil, &RootMismatchError{axis, index}
+		}
+		trees[index] = tree
+	}
+
+	return trees[index], nil
+}
+
+// buildTree returns a tree holding all chunks of the row or column at index.
+func (ds *dataSquare) buildTree(axis Axis, index uint) Tree {
+	tree := ds.newTree(axis, index)
+	data := ds.Row(index)
+	if axis == ColumnAxis {
+		data = ds.Column(index)
+	}
+	for j := uint(0); j < ds.width; j++ {
+		tree.Push(data[j])
+	}
+	return tree
+}
+
 func (ds *dataSquare) computeRowProof(x uint, y uint) ([]byte, [][]byte, uint, uint, error) {
-	tree := merkletree.New(ds.hasher)
-	err := tree.SetIndex(uint64(y))
+	tree, err := ds.tree(RowAxis, x)
 	if err != nil {
 		return nil, nil, 0, 0, err
 	}
-	data := ds.Row(x)
-
-	for i := uint(0); i < ds.width; i++ {
-		tree.Push(data[i])
+	merkleRoot, proof, numLeaves, err := tree.Prove(y)
+	if err != nil {
+		return nil, nil, 0, 0, err
 	}
-
-	merkleRoot, proof, proofIndex, numLeaves := tree.Prove()
-	return merkleRoot, proof, uint(proofIndex), uint(numLeaves), nil
+	return merkleRoot, proof, y, numLeaves, nil
 }
 
 func (ds *dataSquare) computeColumnProof(x uint, y uint) ([]byte, [][]byte, uint, uint, error) {
-	tree := merkletree.New(ds.hasher)
-	err := tree.SetIndex(uint64(x))
+	tree, err := ds.tree(ColumnAxis, y)
+	if err != nil {
+		return nil, nil, 0, 0, err
+	}
+	merkleRoot, proof, numLeaves, err := tree.Prove(x)
 	if err != nil {
 		return nil, nil, 0, 0, err
 	}
-	data := ds.Column(y)
+	return merkleRoot, proof, x, numLeaves, nil
+}
 
-	for i := uint(0); i < ds.width; i++ {
-		tree.Push(data[i])
+// newTree creates an empty tree for the row or column at index.
+func (ds *dataSquare) newTree(axis Axis, index uint) Tree {
+	if ds.config.newTree != nil {
+		return ds.config.newTree(axis, index)
 	}
+	return NewDefaultTree(ds.config.newHasher)
+}
 
-	merkleRoot, proof, proofIndex, numLeaves := tree.Prove()
-	return merkleRoot, proof, uint(proofIndex), uint(numLeaves), nil
+// release returns the buffer of the square to the buffer pool it was taken
+// from. The square must not be used afterwards.
+func (ds *dataSquare) release() {
+	if ds.pooled {
+		ds.config.putBuffer(ds.buf)
+	}
+	ds.buf = nil
+	ds.square = nil
+	ds.pooled = false
 }
 
 // Cell returns a single chunk at a specific cell.
@@ -228,13 +426,19 @@ func (ds *dataSquare) Cell(x uint, y uint) []byte {
 	return cell
 }
 
-func (ds *dataSquare) setCell(x uint, y uint, newChunk []byte) {
-	ds.square[x][y] = newChunk
-	ds.resetRoots()
+func (ds *dataSquare) setCell(x uint, y uint, newChunk []byte) error {
+	if uint(len(newChunk)) != ds.chunkSize {
+		return errors.New("chunk size does not match square chunk size")
+	}
+	copy(ds.square[x][y], newChunk)
+	ds.invalidateCell(x, y)
+	return nil
 }
 
+// flattened returns all cells row by row. The cells are views into the
+// square.
 func (ds *dataSquare) flattened() [][]byte {
-	flattened := [][]byte(nil)
+	flattened := make([][]byte, 0, ds.width*ds.width)
 	for _, data := range ds.square {
 		flattened = append(flattened, data...)
 	}
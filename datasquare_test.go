@@ -1,7 +1,10 @@
 package rsmt2d
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"reflect"
+	"sync"
 	"testing"
 )
 
@@ -56,6 +59,44 @@ func TestExtendSquare(t *testing.T) {
 	}
 }
 
+func TestContiguousBuffer(t *testing.T) {
+	data := [][]byte{{1, 2}, {3, 4}, {5, 6}, {7, 8}}
+	ds, err := newDataSquare(data)
+	if err != nil {
+		panic(err)
+	}
+	if !bytes.Equal(ds.buf, []byte{1, 2, 3, 4, 5, 6, 7, 8}) {
+		t.Errorf("square is not backed by a contiguous buffer")
+	}
+	data[0][0] = 42
+	if ds.Cell(0, 0)[0] != 1 {
+		t.Errorf("square aliases the input chunks")
+	}
+
+	if err := ds.setCell(1, 0, []byte{9, 10}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(ds.buf[4:6], []byte{9, 10}) {
+		t.Errorf("setCell did not write to the buffer")
+	}
+	if ds.setCell(1, 1, []byte{1, 2, 3}) == nil || ds.setCell(1, 1, []byte{1}) == nil {
+		t.Errorf("setCell accepted a chunk of the wrong size")
+	}
+	if !bytes.Equal(ds.buf[6:8], []byte{7, 8}) {
+		t.Errorf("a rejected chunk was written to the buffer")
+	}
+	if cap(ds.square[0][0]) != 2 {
+		t.Errorf("cells must be capped at the chunk size")
+	}
+
+	if err := ds.extendSquare(2, []byte{0, 0}); err != nil {
+		panic(err)
+	}
+	if len(ds.buf) != 32 || !bytes.Equal(ds.buf[:12], []byte{1, 2, 3, 4, 0, 0, 0, 0, 9, 10, 7, 8}) {
+		t.Errorf("extended square is not backed by a contiguous buffer")
+	}
+}
+
 func TestRoots(t *testing.T) {
 	result, err := newDataSquare([][]byte{{1, 2}})
 	if err != nil {
@@ -66,6 +107,53 @@ func TestRoots(t *testing.T) {
 	}
 }
 
+func TestIncrementalRoots(t *testing.T) {
+	trees := 0
+	newTree := func(axis Axis, index uint) Tree {
+		trees++
+		return NewDefaultTree(sha256.New)
+	}
+	// The trees are counted without synchronization.
+	ds, err := newConfiguredDataSquare([][]byte{{1}, {2}, {3}, {4}, {5}, {6}, {7}, {8}, {9}}, newConfig([]Option{WithParallelism(1)}))
+	if err != nil {
+		panic(err)
+	}
+	ds.SetTree(newTree)
+
+	oldRowRoots, oldColumnRoots := ds.RowRoots(), ds.ColumnRoots()
+	if trees != 6 {
+		t.Errorf("expected 6 trees, got %d", trees)
+	}
+
+	ds.setCell(1, 2, []byte{42})
+	ds.setCell(1, 0, []byte{43})
+	trees = 0
+	rowRoots, columnRoots := ds.RowRoots(), ds.ColumnRoots()
+	if trees != 3 {
+		t.Errorf("expected only row 1 and columns 0 and 2 to be recomputed, got %d trees", trees)
+	}
+
+	expected, err := newDataSquare([][]byte{{1}, {2}, {3}, {43}, {5}, {42}, {7}, {8}, {9}})
+	if err != nil {
+		panic(err)
+	}
+	if !reflect.DeepEqual(rowRoots, expected.RowRoots()) || !reflect.DeepEqual(columnRoots, expected.ColumnRoots()) {
+		t.Errorf("incrementally computed roots do not match")
+	}
+	if bytes.Equal(oldRowRoots[1], rowRoots[1]) || bytes.Equal(oldColumnRoots[2], columnRoots[2]) {
+		t.Errorf("roots returned before the change must not be updated")
+	}
+
+	if err := ds.setRowSlice(2, 0, [][]byte{{1}, {2}}); err != nil {
+		panic(err)
+	}
+	trees = 0
+	ds.RowRoots()
+	if trees != 3 {
+		t.Errorf("expected row 2 and columns 0 and 1 to be recomputed, got %d trees", trees)
+	}
+}
+
 func TestProofs(t *testing.T) {
 	result, err := newDataSquare([][]byte{{1, 2}, {3, 4}, {5, 6}, {7, 8}})
 	if err != nil {
@@ -103,3 +191,69 @@ func TestProofs(t *testing.T) {
 		t.Errorf("computing column proof for (1, 1) in 2x2 square failed; expecting number of leaves to be 2")
 	}
 }
+
+func TestRootsParallel(t *testing.T) {
+	data := make([][]byte, 64)
+	for i := range data {
+		data[i] = []byte{byte(i), byte(i * 3)}
+	}
+
+	sequential, err := newDataSquare(data)
+	if err != nil {
+		panic(err)
+	}
+	sequential.config = newConfig([]Option{WithParallelism(1)})
+
+	parallel, err := newDataSquare(data)
+	if err != nil {
+		panic(err)
+	}
+	parallel.config = newConfig([]Option{WithParallelism(8)})
+
+	if !reflect.DeepEqual(sequential.RowRoots(), parallel.RowRoots()) ||
+		!reflect.DeepEqual(sequential.ColumnRoots(), parallel.ColumnRoots()) {
+		t.Errorf("roots computed in parallel differ from sequentially computed roots")
+	}
+
+	custom, err := newDataSquare(data)
+	if err != nil {
+		panic(err)
+	}
+	custom.config = parallel.config
+	custom.SetHasher(sha256.New)
+	if !reflect.DeepEqual(sequential.RowRoots(), custom.RowRoots()) ||
+		!reflect.DeepEqual(sequential.ColumnRoots(), custom.ColumnRoots()) {
+		t.Errorf("roots computed with a hasher set by SetHasher differ")
+	}
+}
+
+func TestConcurrentReads(t *testing.T) {
+	data := make([][]byte, 16)
+	for i := range data {
+		data[i] = []byte{byte(i)}
+	}
+	ds, err := newDataSquare(data)
+	if err != nil {
+		panic(err)
+	}
+	expected, err := newDataSquare(data)
+	if err != nil {
+		panic(err)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			if !reflect.DeepEqual(ds.RowRoots(), expected.RowRoots()) {
+				t.Errorf("unexpected row roots")
+			}
+			root, _, _, _, err := ds.computeRowProof(uint(g%4), uint(g/4))
+			if err != nil || !bytes.Equal(root, expected.RowRoots()[g%4]) {
+				t.Errorf("unexpected row proof root, err: %v", err)
+			}
+		}(g)
+	}
+	wg.Wait()
+}
@@ -2,10 +2,10 @@ package rsmt2d
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
-
-	"gonum.org/v1/gonum/mat"
+	"math"
 )
 
 const (
@@ -35,61 +35,154 @@ func (e *ByzantineColumnError) Error() string {
 
 // UnrepairableDataSquareError is thrown when there is insufficient chunks to repair the square.
 type UnrepairableDataSquareError struct {
+	// Rows and Columns are the indices of the rows and columns that are still
+	// incomplete.
+	Rows    []uint
+	Columns []uint
+}
+
+func newUnrepairableDataSquareError(mask *AvailabilityMask) *UnrepairableDataSquareError {
+	e := &UnrepairableDataSquareError{}
+	for i := uint(0); i < mask.Width(); i++ {
+		if !mask.IsRowComplete(i) {
+			e.Rows = append(e.Rows, i)
+		}
+		if !mask.IsColumnComplete(i) {
+			e.Columns = append(e.Columns, i)
+		}
+	}
+	return e
 }
 
 func (e *UnrepairableDataSquareError) Error() string {
-	return "failed to solve data square"
+	return fmt.Sprintf("failed to solve data square: %d rows and %d columns incomplete", len(e.Rows), len(e.Columns))
 }
 
 // RepairExtendedDataSquare repairs an incomplete extended data square, against its expected row and column merkle roots.
-// Missing data chunks should be represented as nil.
-func RepairExtendedDataSquare(rowRoots [][]byte, columnRoots [][]byte, data [][]byte, codec CodecType) (*ExtendedDataSquare, error) {
-	matrixData := make([]float64, len(data))
+// Missing data chunks should be represented as nil. The data slice and its chunks are not modified.
+// The options must match those the expected roots were computed with.
+func RepairExtendedDataSquare(rowRoots [][]byte, columnRoots [][]byte, data [][]byte, codec CodecType, opts ...Option) (*ExtendedDataSquare, error) {
+	return RepairExtendedDataSquareWithContext(context.Background(), rowRoots, columnRoots, data, codec, opts...)
+}
+
+// RepairExtendedDataSquareWithContext is RepairExtendedDataSquare, stopping
+// between the repair of rows and columns once ctx is done. It then returns
+// ctx.Err() together with the partially repaired square, in which all
+// repaired rows and columns match their roots.
+func RepairExtendedDataSquareWithContext(ctx context.Context, rowRoots [][]byte, columnRoots [][]byte, data [][]byte, codec CodecType, opts ...Option) (*ExtendedDataSquare, error) {
+	width := uint(math.Sqrt(float64(len(data))))
+	if width*width != uint(len(data)) {
+		return nil, errors.New("number of chunks must be a square number")
+	}
+	return RepairExtendedDataSquareWithMask(ctx, rowRoots, columnRoots, data, availabilityMaskOf(data, width), codec, opts...)
+}
+
+// RepairExtendedDataSquareWithMask is RepairExtendedDataSquareWithContext,
+// with the available chunks given by mask rather than by non-nil entries of
+// data. Entries of unavailable chunks are ignored and may be nil.
+func RepairExtendedDataSquareWithMask(ctx context.Context, rowRoots [][]byte, columnRoots [][]byte, data [][]byte, mask *AvailabilityMask, codec CodecType, opts ...Option) (*ExtendedDataSquare, error) {
+	result, err := RepairExtendedDataSquareWithResult(ctx, rowRoots, columnRoots, data, mask, codec, opts...)
+	if result == nil {
+		return nil, err
+	}
+	if _, ok := err.(*UnrepairableDataSquareError); ok {
+		if result.Square != nil {
+			result.Square.release()
+		}
+		return nil, err
+	}
+	return result.Square, err
+}
+
+// RepairResult is the outcome of a repair.
+type RepairResult struct {
+	// Square is the repaired square. It is nil if no chunk was available.
+	Square *ExtendedDataSquare
+	// Reconstructed marks the chunks that were missing from the input and
+	// have been rebuilt by the repair.
+	Reconstructed *AvailabilityMask
+	// Available marks the chunks of Square that are known. Chunks missing
+	// from it are zero.
+	Available *AvailabilityMask
+}
+
+// RepairExtendedDataSquareWithResult is RepairExtendedDataSquareWithMask,
+// also reporting which chunks were reconstructed. Neither data nor mask are
+// modified.
+//
+// If the square cannot be repaired from the available chunks, the result
+// holds the partially repaired square, in which all repaired rows and columns
+// match their roots, together with an UnrepairableDataSquareError listing the
+// incomplete rows and columns. If no chunk is available at all, the chunk size
+// is unknown and the result has no square.
+func RepairExtendedDataSquareWithResult(ctx context.Context, rowRoots [][]byte, columnRoots [][]byte, data [][]byte, mask *AvailabilityMask, codec CodecType, opts ...Option) (*RepairResult, error) {
+	if mask == nil {
+		return nil, errors.New("mask must not be nil")
+	}
+	if mask.Width()*mask.Width() != uint(len(data)) {
+		return nil, errors.New("mask width does not match number of chunks")
+	}
+
 	var chunkSize int
 	for i := range data {
-		if data[i] == nil {
-			matrixData[i] = 0
-		} else {
-			matrixData[i] = 1
-			if chunkSize == 0 {
-				chunkSize = len(data[i])
-			}
+		if mask.IsSet(uint(i)/mask.Width(), uint(i)%mask.Width()) {
+			chunkSize = len(data[i])
+			break
 		}
 	}
 
 	if chunkSize == 0 {
-		return nil, &UnrepairableDataSquareError{}
+		return &RepairResult{Reconstructed: NewAvailabilityMask(mask.Width()), Available: mask.Clone()}, newUnrepairableDataSquareError(mask)
 	}
 
-	fillerChunk := bytes.Repeat([]byte{0}, chunkSize)
+	fillerChunk := make([]byte, chunkSize)
+	squareData := make([][]byte, len(data))
 	for i := range data {
-		if data[i] == nil {
-			data[i] = make([]byte, chunkSize)
-			copy(data[i], fillerChunk)
+		if mask.IsSet(uint(i)/mask.Width(), uint(i)%mask.Width()) {
+			squareData[i] = data[i]
+		} else {
+			squareData[i] = fillerChunk
 		}
 	}
 
-	eds, err := ImportExtendedDataSquare(data, codec)
+	eds, err := importExtendedDataSquare(squareData, codec, newConfig(opts))
 	if err != nil {
 		return nil, err
 	}
 
-	matrix := mat.NewDense(int(eds.width), int(eds.width), matrixData)
+	repaired := mask.Clone()
 
-	err = eds.prerepairSanityCheck(rowRoots, columnRoots, *matrix)
+	err = eds.prerepairSanityCheck(ctx, rowRoots, columnRoots, repaired)
 	if err != nil {
-		return nil, err
+		return eds.repairFailed(ctx, err, mask, repaired)
 	}
 
-	err = eds.solveCrossword(rowRoots, columnRoots, *matrix)
+	err = eds.solveCrossword(ctx, rowRoots, columnRoots, repaired)
 	if err != nil {
-		return nil, err
+		return eds.repairFailed(ctx, err, mask, repaired)
 	}
 
-	return eds, err
+	if err := eds.validateConfigured(); err != nil {
+		return eds.repairFailed(ctx, err, mask, repaired)
+	}
+
+	return &RepairResult{Square: eds, Reconstructed: repaired.without(mask), Available: repaired}, nil
+}
+
+// repairFailed releases the square being repaired, unless the repair was
+// stopped by ctx or ran out of chunks, in which case the partially repaired
+// square is returned. input and repaired are the masks of the chunks available
+// before and after the repair.
+func (eds *ExtendedDataSquare) repairFailed(ctx context.Context, err error, input *AvailabilityMask, repaired *AvailabilityMask) (*RepairResult, error) {
+	_, unrepairable := err.(*UnrepairableDataSquareError)
+	if err == ctx.Err() || unrepairable {
+		return &RepairResult{Square: eds, Reconstructed: repaired.without(input), Available: repaired}, err
+	}
+	eds.release()
+	return nil, err
 }
 
-func (eds *ExtendedDataSquare) solveCrossword(rowRoots [][]byte, columnRoots [][]byte, mask mat.Dense) error {
+func (eds *ExtendedDataSquare) solveCrossword(ctx context.Context, rowRoots [][]byte, columnRoots [][]byte, mask *AvailabilityMask) error {
 	// Keep repeating until the square is solved
 	var solved bool
 	var progressMade bool
@@ -104,14 +197,24 @@ func (eds *ExtendedDataSquare) solveCrossword(rowRoots [][]byte, columnRoots [][
 		// Loop through every row and column, attempt to rebuild each row or column if incomplete
 		for i := uint(0); i < eds.width; i++ {
 			for mode := range []int{row, column} {
-				var vectorMask mat.Vector
-				if mode == row {
-					vectorMask = mask.RowView(int(i))
-				} else if mode == column {
-					vectorMask = mask.ColView(int(i))
+				if err := ctx.Err(); err != nil {
+					return err
 				}
 
-				if !vecIsTrue(vectorMask) { // row/column incomplete
+				isAvailable := func(j uint) bool {
+					if mode == row {
+						return mask.IsSet(i, j)
+					}
+					return mask.IsSet(j, i)
+				}
+				count := mask.RowCount(i)
+				if mode == column {
+					count = mask.ColumnCount(i)
+				}
+
+				if count < eds.originalDataWidth { // too few shares to rebuild
+					solved = false
+				} else if count < eds.width { // row/column incomplete
 					// Prepare shares
 					var vectorData [][]byte
 					if mode == row {
@@ -121,7 +224,7 @@ func (eds *ExtendedDataSquare) solveCrossword(rowRoots [][]byte, columnRoots [][
 					}
 					shares = make([][]byte, eds.width)
 					for j := uint(0); j < eds.width; j++ {
-						if vectorMask.AtVec(int(j)) == 1 {
+						if isAvailable(j) {
 							shares[j] = vectorData[j]
 						}
 					}
@@ -137,14 +240,17 @@ func (eds *ExtendedDataSquare) solveCrossword(rowRoots [][]byte, columnRoots [][
 						// Insert rebuilt shares into square
 						for p, s := range rebuiltShares {
 							if mode == row {
-								eds.setCell(i, uint(p), s)
+								err = eds.setCell(i, uint(p), s)
 							} else if mode == column {
-								eds.setCell(uint(p), i, s)
+								err = eds.setCell(uint(p), i, s)
+							}
+							if err != nil {
+								return err
 							}
 						}
 
 						// Rebuild extended part if incomplete
-						if !vecSliceIsTrue(vectorMask, int(eds.originalDataWidth), int(eds.width)) {
+						if !isRangeAvailable(isAvailable, eds.originalDataWidth, eds.width) {
 							if mode == row {
 								rebuiltExtendedShares, err = Encode(eds.rowSlice(i, 0, eds.originalDataWidth), eds.codec)
 							} else if mode == column {
@@ -155,9 +261,12 @@ func (eds *ExtendedDataSquare) solveCrossword(rowRoots [][]byte, columnRoots [][
 							}
 							for p, s := range rebuiltExtendedShares {
 								if mode == row {
-									eds.setCell(i, eds.originalDataWidth+uint(p), s)
+									err = eds.setCell(i, eds.originalDataWidth+uint(p), s)
 								} else if mode == column {
-									eds.setCell(eds.originalDataWidth+uint(p), i, s)
+									err = eds.setCell(eds.originalDataWidth+uint(p), i, s)
+								}
+								if err != nil {
+									return err
 								}
 							}
 						}
@@ -175,15 +284,13 @@ func (eds *ExtendedDataSquare) solveCrossword(rowRoots [][]byte, columnRoots [][
 
 						// Check that newly completed orthogonal vectors match their new merkle roots
 						for j := uint(0); j < eds.width; j++ {
-							if vectorMask.AtVec(int(j)) == 0 {
+							if !isAvailable(j) {
 								if mode == row {
-									adjMask := mask.ColView(int(j))
-									if vecNumTrue(adjMask) == adjMask.Len()-1 && !bytes.Equal(eds.ColumnRoots()[j], columnRoots[j]) {
+									if mask.ColumnCount(j) == eds.width-1 && !bytes.Equal(eds.ColumnRoots()[j], columnRoots[j]) {
 										return &ByzantineColumnError{j, edsBackup}
 									}
 								} else if mode == column {
-									adjMask := mask.RowView(int(j))
-									if vecNumTrue(adjMask) == adjMask.Len()-1 && !bytes.Equal(eds.RowRoots()[j], rowRoots[j]) {
+									if mask.RowCount(j) == eds.width-1 && !bytes.Equal(eds.RowRoots()[j], rowRoots[j]) {
 										return &ByzantineRowError{j, edsBackup}
 									}
 								}
@@ -192,13 +299,9 @@ func (eds *ExtendedDataSquare) solveCrossword(rowRoots [][]byte, columnRoots [][
 
 						// Set vector mask to true
 						if mode == row {
-							for j := 0; j < int(eds.width); j++ {
-								mask.Set(int(i), j, 1)
-							}
+							mask.SetRow(i)
 						} else if mode == column {
-							for j := 0; j < int(eds.width); j++ {
-								mask.Set(j, int(i), 1)
-							}
+							mask.SetColumn(i)
 						}
 					} else { // repair unsuccessful
 						solved = false
@@ -210,40 +313,47 @@ func (eds *ExtendedDataSquare) solveCrossword(rowRoots [][]byte, columnRoots [][
 		if solved {
 			break
 		} else if !progressMade {
-			return &UnrepairableDataSquareError{}
+			return newUnrepairableDataSquareError(mask)
 		}
 	}
 
 	return nil
 }
 
-func (eds *ExtendedDataSquare) prerepairSanityCheck(rowRoots [][]byte, columnRoots [][]byte, mask mat.Dense) error {
+func (eds *ExtendedDataSquare) prerepairSanityCheck(ctx context.Context, rowRoots [][]byte, columnRoots [][]byte, mask *AvailabilityMask) error {
 	var shares [][]byte
 	var err error
 	for i := uint(0); i < eds.width; i++ {
-		rowMask := mask.RowView(int(i))
-		columnMask := mask.ColView(int(i))
-		if (vecIsTrue(rowMask) && !bytes.Equal(rowRoots[i], eds.RowRoots()[i])) || (vecIsTrue(columnMask) && !bytes.Equal(columnRoots[i], eds.ColumnRoots()[i])) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		rowComplete := mask.IsRowComplete(i)
+		columnComplete := mask.IsColumnComplete(i)
+		if (rowComplete && !bytes.Equal(rowRoots[i], eds.RowRoots()[i])) || (columnComplete && !bytes.Equal(columnRoots[i], eds.ColumnRoots()[i])) {
 			return errors.New("bad roots input")
 		}
 
-		if vecIsTrue(rowMask) {
+		if rowComplete {
 			shares, err = Encode(eds.rowSlice(i, 0, eds.originalDataWidth), eds.codec)
 			if err != nil {
 				return err
 			}
 			if !bytes.Equal(flattenChunks(shares), flattenChunks(eds.rowSlice(i, eds.originalDataWidth, eds.originalDataWidth))) {
-				return &ByzantineRowError{i, *eds}
+				// The square is released by the caller.
+				edsBackup, _ := eds.deepCopy()
+				return &ByzantineRowError{i, edsBackup}
 			}
 		}
 
-		if vecIsTrue(columnMask) {
+		if columnComplete {
 			shares, err = Encode(eds.columnSlice(0, i, eds.originalDataWidth), eds.codec)
 			if err != nil {
 				return err
 			}
 			if !bytes.Equal(flattenChunks(shares), flattenChunks(eds.columnSlice(eds.originalDataWidth, i, eds.originalDataWidth))) {
-				return &ByzantineColumnError{i, *eds}
+				edsBackup, _ := eds.deepCopy()
+				return &ByzantineColumnError{i, edsBackup}
 			}
 		}
 	}
@@ -251,33 +361,13 @@ func (eds *ExtendedDataSquare) prerepairSanityCheck(rowRoots [][]byte, columnRoo
 	return nil
 }
 
-func vecIsTrue(vec mat.Vector) bool {
-	for i := 0; i < vec.Len(); i++ {
-		if vec.AtVec(i) == 0 {
-			return false
-		}
-	}
-
-	return true
-}
-
-func vecSliceIsTrue(vec mat.Vector, start int, end int) bool {
-	for i := start; i < end; i++ {
-		if vec.AtVec(i) == 0 {
+// isRangeAvailable reports whether all indices in [start, end) are available.
+func isRangeAvailable(isAvailable func(j uint) bool, start uint, end uint) bool {
+	for j := start; j < end; j++ {
+		if !isAvailable(j) {
 			return false
 		}
 	}
 
 	return true
 }
-
-func vecNumTrue(vec mat.Vector) int {
-	var counter int
-	for i := 0; i < vec.Len(); i++ {
-		if vec.AtVec(i) == 1 {
-			counter++
-		}
-	}
-
-	return counter
-}
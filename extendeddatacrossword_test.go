@@ -2,6 +2,9 @@ package rsmt2d
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha512"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -9,7 +12,7 @@ import (
 
 func TestRepairExtendedDataSquare(t *testing.T) {
 	for _, codec := range codecs {
-		codec := codec.codecType()
+		codec := codec.CodecType()
 
 		bufferSize := 64
 		ones := bytes.Repeat([]byte{1}, bufferSize)
@@ -108,3 +111,274 @@ func TestRepairExtendedDataSquare(t *testing.T) {
 		}
 	}
 }
+
+func TestByzantineErrorLastGoodSquare(t *testing.T) {
+	original, err := ComputeExtendedDataSquare([][]byte{{1, 2}, {3, 4}, {5, 6}, {7, 8}}, RSGF8)
+	if err != nil {
+		panic(err)
+	}
+	corrupted, err := original.deepCopy()
+	if err != nil {
+		panic(err)
+	}
+	assert.NoError(t, corrupted.setCell(0, 0, []byte{66, 66}))
+
+	for _, opts := range [][]Option{nil, {WithBufferPool(newSizedPool())}} {
+		flattened := corrupted.flattened()
+		flattened[15] = nil
+		_, err = RepairExtendedDataSquare(corrupted.RowRoots(), corrupted.ColumnRoots(), flattened, RSGF8, opts...)
+		byzantine, ok := err.(*ByzantineRowError)
+		if !assert.True(t, ok, "got %v", err) {
+			continue
+		}
+
+		// The square of the error must outlive the failed repair, and its
+		// buffer must not be reused by the next square.
+		_, err = ImportExtendedDataSquare(original.flattened(), RSGF8, opts...)
+		assert.NoError(t, err)
+		assert.Equal(t, corrupted.Row(0), byzantine.LastGoodSquare.Row(0))
+	}
+}
+
+func TestRepairExtendedDataSquareWithHasher(t *testing.T) {
+	chunks := [][]byte{
+		bytes.Repeat([]byte{1}, 64), bytes.Repeat([]byte{2}, 64),
+		bytes.Repeat([]byte{3}, 64), bytes.Repeat([]byte{4}, 64),
+	}
+	original, err := ComputeExtendedDataSquare(chunks, RSGF8, WithHasher(sha512.New))
+	if err != nil {
+		panic(err)
+	}
+
+	flattened := original.flattened()
+	flattened[0], flattened[1] = nil, nil
+	_, err = RepairExtendedDataSquare(original.RowRoots(), original.ColumnRoots(), flattened, RSGF8, WithHasher(sha512.New))
+	if err != nil {
+		t.Errorf("unexpected err while repairing data square with custom hasher: %v", err)
+	}
+
+	flattened = original.flattened()
+	flattened[0], flattened[1] = nil, nil
+	_, err = RepairExtendedDataSquare(original.RowRoots(), original.ColumnRoots(), flattened, RSGF8)
+	if err == nil {
+		t.Errorf("repairing against roots of a different hasher did not fail")
+	}
+}
+
+// cancellingCodec is an RSGF8 codec cancelling a context once it has encoded
+// or decoded a number of times.
+type cancellingCodec struct {
+	*rsGF8Codec
+	ct CodecType
+
+	mu                 sync.Mutex
+	encodes            int
+	decodes            int
+	cancelAfterEncodes int
+	cancelAfterDecodes int
+	cancel             context.CancelFunc
+}
+
+func newCancellingCodec(t *testing.T, cancelAfterEncodes, cancelAfterDecodes int, cancel context.CancelFunc) *cancellingCodec {
+	c := &cancellingCodec{
+		rsGF8Codec:         newRSGF8Codec(),
+		ct:                 NewCodecType(),
+		cancelAfterEncodes: cancelAfterEncodes,
+		cancelAfterDecodes: cancelAfterDecodes,
+		cancel:             cancel,
+	}
+	registerTestCodec(t, c.ct, c)
+	return c
+}
+
+func (c *cancellingCodec) CodecType() CodecType {
+	return c.ct
+}
+
+func (c *cancellingCodec) Encode(data [][]byte) ([][]byte, error) {
+	c.mu.Lock()
+	c.encodes++
+	if c.encodes == c.cancelAfterEncodes {
+		c.cancel()
+	}
+	c.mu.Unlock()
+	return c.rsGF8Codec.Encode(data)
+}
+
+func (c *cancellingCodec) Decode(data [][]byte) ([][]byte, error) {
+	c.mu.Lock()
+	c.decodes++
+	if c.decodes == c.cancelAfterDecodes {
+		c.cancel()
+	}
+	c.mu.Unlock()
+	return c.rsGF8Codec.Decode(data)
+}
+
+func TestComputeExtendedDataSquareWithContext(t *testing.T) {
+	chunks := make([][]byte, 16)
+	for i := range chunks {
+		chunks[i] = []byte{byte(i)}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	codec := newCancellingCodec(t, 2, 0, cancel)
+	eds, err := ComputeExtendedDataSquareWithContext(ctx, chunks, codec.ct, WithParallelism(1))
+	assert.Nil(t, eds)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 2, codec.encodes, "encoding should stop once the context is cancelled")
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	_, err = ComputeExtendedDataSquareWithContext(ctx, chunks, RSGF8, WithParallelism(4))
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestRepairExtendedDataSquareWithContext(t *testing.T) {
+	chunks := make([][]byte, 16)
+	for i := range chunks {
+		chunks[i] = []byte{byte(i)}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	codec := newCancellingCodec(t, 0, 1, cancel)
+	original, err := ComputeExtendedDataSquare(chunks, codec.ct)
+	if err != nil {
+		panic(err)
+	}
+
+	// Row 0 is repaired first, then the context is cancelled.
+	incomplete := func() [][]byte {
+		flattened := original.flattened()
+		flattened[0], flattened[1], flattened[9], flattened[18] = nil, nil, nil, nil
+		return flattened
+	}
+	partial, err := RepairExtendedDataSquareWithContext(ctx, original.RowRoots(), original.ColumnRoots(), incomplete(), codec.ct)
+	assert.Equal(t, context.Canceled, err)
+	if assert.NotNil(t, partial, "the partially repaired square should be returned") {
+		assert.Equal(t, original.Row(0), partial.Row(0))
+		assert.Equal(t, original.RowRoots()[0], partial.RowRoots()[0])
+		assert.NotEqual(t, original.Row(2), partial.Row(2))
+	}
+
+	repaired, err := RepairExtendedDataSquare(original.RowRoots(), original.ColumnRoots(), incomplete(), codec.ct)
+	assert.NoError(t, err)
+	assert.Equal(t, original.flattened(), repaired.flattened())
+}
+
+func TestRepairExtendedDataSquareWithMask(t *testing.T) {
+	chunks := make([][]byte, 16)
+	for i := range chunks {
+		chunks[i] = bytes.Repeat([]byte{byte(i + 1)}, 64)
+	}
+	original, err := ComputeExtendedDataSquare(chunks, RSGF8)
+	if err != nil {
+		panic(err)
+	}
+
+	data := original.flattened()
+	mask := NewAvailabilityMask(original.Width())
+	for i := range data {
+		row, column := uint(i)/original.Width(), uint(i)%original.Width()
+		if row < 4 && column < 4 {
+			// Unavailable entries are ignored, whatever they hold.
+			data[i] = []byte{0xAB}
+			continue
+		}
+		mask.Set(row, column)
+	}
+
+	repaired, err := RepairExtendedDataSquareWithMask(context.Background(), original.RowRoots(), original.ColumnRoots(), data, mask, RSGF8)
+	assert.NoError(t, err)
+	assert.Equal(t, original.flattened(), repaired.flattened())
+	assert.Equal(t, uint(48), mask.Count(), "the mask of the caller must not change")
+
+	_, err = RepairExtendedDataSquareWithMask(context.Background(), original.RowRoots(), original.ColumnRoots(), data, NewAvailabilityMask(7), RSGF8)
+	assert.Error(t, err)
+	_, err = RepairExtendedDataSquareWithMask(context.Background(), original.RowRoots(), original.ColumnRoots(), data, NewAvailabilityMask(8), RSGF8)
+	assert.IsType(t, &UnrepairableDataSquareError{}, err)
+	_, err = RepairExtendedDataSquareWithMask(context.Background(), original.RowRoots(), original.ColumnRoots(), data, nil, RSGF8)
+	assert.EqualError(t, err, "mask must not be nil")
+
+	_, err = RepairExtendedDataSquare(original.RowRoots(), original.ColumnRoots(), data[:63], RSGF8)
+	assert.EqualError(t, err, "number of chunks must be a square number")
+}
+
+func TestRepairExtendedDataSquareWithResult(t *testing.T) {
+	chunks := [][]byte{
+		bytes.Repeat([]byte{1}, 64), bytes.Repeat([]byte{2}, 64),
+		bytes.Repeat([]byte{3}, 64), bytes.Repeat([]byte{4}, 64),
+	}
+	original, err := ComputeExtendedDataSquare(chunks, RSGF8)
+	if err != nil {
+		panic(err)
+	}
+
+	data := original.flattened()
+	data[0], data[5], data[6], data[15] = nil, nil, nil, nil
+	input := original.flattened()
+	input[0], input[5], input[6], input[15] = nil, nil, nil, nil
+	mask := availabilityMaskOf(data, original.Width())
+
+	for attempt := 0; attempt < 2; attempt++ {
+		result, err := RepairExtendedDataSquareWithResult(context.Background(), original.RowRoots(), original.ColumnRoots(), data, mask, RSGF8)
+		assert.NoError(t, err)
+		assert.Equal(t, original.flattened(), result.Square.flattened())
+		assert.Equal(t, input, data, "the input of the caller must not change")
+		assert.Equal(t, uint(12), mask.Count())
+
+		assert.Equal(t, uint(4), result.Reconstructed.Count())
+		for i := range data {
+			assert.Equal(t, data[i] == nil, result.Reconstructed.IsSet(uint(i)/4, uint(i)%4))
+		}
+	}
+
+	_, err = RepairExtendedDataSquare(original.RowRoots(), original.ColumnRoots(), data, RSGF8)
+	assert.NoError(t, err)
+	assert.Equal(t, input, data, "the input of the caller must not change")
+}
+
+func TestRepairExtendedDataSquarePartialResult(t *testing.T) {
+	chunks := [][]byte{
+		bytes.Repeat([]byte{1}, 64), bytes.Repeat([]byte{2}, 64),
+		bytes.Repeat([]byte{3}, 64), bytes.Repeat([]byte{4}, 64),
+	}
+	original, err := ComputeExtendedDataSquare(chunks, RSGF8)
+	if err != nil {
+		panic(err)
+	}
+
+	// Row 3 can be repaired, which then completes enough of column 2 to
+	// repair it, but no other row or column.
+	mask := NewAvailabilityMask(4)
+	mask.Set(3, 0)
+	mask.Set(3, 1)
+	mask.Set(0, 2)
+	data := original.flattened()
+
+	result, err := RepairExtendedDataSquareWithResult(context.Background(), original.RowRoots(), original.ColumnRoots(), data, mask, RSGF8)
+	assert.Equal(t, &UnrepairableDataSquareError{Rows: []uint{0, 1, 2}, Columns: []uint{0, 1, 3}}, err)
+	if assert.NotNil(t, result) {
+		assert.Equal(t, original.Row(3), result.Square.Row(3))
+		assert.Equal(t, original.Column(2), result.Square.Column(2))
+		assert.Equal(t, uint(7), result.Available.Count())
+		assert.True(t, result.Available.IsRowComplete(3))
+		assert.True(t, result.Available.IsColumnComplete(2))
+		assert.Equal(t, uint(4), result.Reconstructed.Count())
+	}
+
+	repaired, err := RepairExtendedDataSquareWithMask(context.Background(), original.RowRoots(), original.ColumnRoots(), data, mask, RSGF8)
+	assert.Nil(t, repaired)
+	assert.IsType(t, &UnrepairableDataSquareError{}, err)
+	// Without any chunk, there is no square to return.
+	result, err = RepairExtendedDataSquareWithResult(context.Background(), original.RowRoots(), original.ColumnRoots(), data, NewAvailabilityMask(4), RSGF8)
+	assert.Equal(t, &UnrepairableDataSquareError{Rows: []uint{0, 1, 2, 3}, Columns: []uint{0, 1, 2, 3}}, err)
+	if assert.NotNil(t, result) {
+		assert.Nil(t, result.Square)
+		assert.Equal(t, uint(0), result.Available.Count())
+		assert.Equal(t, uint(4), result.Available.Width())
+		assert.Equal(t, uint(0), result.Reconstructed.Count())
+	}
+	repaired, err = RepairExtendedDataSquare(original.RowRoots(), original.ColumnRoots(), make([][]byte, 16), RSGF8)
+	assert.Nil(t, repaired)
+	assert.IsType(t, &UnrepairableDataSquareError{}, err)
+}
@@ -3,6 +3,7 @@ package rsmt2d
 
 import (
 	"bytes"
+	"context"
 	"errors"
 )
 
@@ -14,23 +15,31 @@ type ExtendedDataSquare struct {
 }
 
 // ComputeExtendedDataSquare computes the extended data square for some chunks of data.
-func ComputeExtendedDataSquare(data [][]byte, codecType CodecType) (*ExtendedDataSquare, error) {
-	if codec, ok := codecs[codecType]; !ok {
+func ComputeExtendedDataSquare(data [][]byte, codecType CodecType, opts ...Option) (*ExtendedDataSquare, error) {
+	return ComputeExtendedDataSquareWithContext(context.Background(), data, codecType, opts...)
+}
+
+// ComputeExtendedDataSquareWithContext is ComputeExtendedDataSquare, stopping
+// between the encoding of rows and columns once ctx is done, in which case it
+// returns ctx.Err().
+func ComputeExtendedDataSquareWithContext(ctx context.Context, data [][]byte, codecType CodecType, opts ...Option) (*ExtendedDataSquare, error) {
+	if codec, ok := GetCodec(codecType); !ok {
 		return nil, errors.New("unsupported codecType")
 	} else {
-		if len(data) > codec.maxChunks() {
+		if len(data) > codec.MaxChunks() {
 			return nil, errors.New("number of chunks exceeds the maximum")
 		}
 	}
 
-	ds, err := newDataSquare(data)
+	ds, err := newConfiguredDataSquare(data, newConfig(opts))
 	if err != nil {
 		return nil, err
 	}
 
 	eds := ExtendedDataSquare{dataSquare: ds, codec: codecType}
-	err = eds.erasureExtendSquare()
+	err = eds.erasureExtendSquare(ctx)
 	if err != nil {
+		eds.release()
 		return nil, err
 	}
 
@@ -38,21 +47,38 @@ func ComputeExtendedDataSquare(data [][]byte, codecType CodecType) (*ExtendedDat
 }
 
 // ImportExtendedDataSquare imports an extended data square, represented as flattened chunks of data.
-func ImportExtendedDataSquare(data [][]byte, codecType CodecType) (*ExtendedDataSquare, error) {
-	if codec, ok := codecs[codecType]; !ok {
+// The square is only checked for consistency if WithValidation or WithExpectedRoots is given.
+func ImportExtendedDataSquare(data [][]byte, codecType CodecType, opts ...Option) (*ExtendedDataSquare, error) {
+	eds, err := importExtendedDataSquare(data, codecType, newConfig(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := eds.validateConfigured(); err != nil {
+		eds.release()
+		return nil, err
+	}
+
+	return eds, nil
+}
+
+// importExtendedDataSquare imports a square without any validation.
+func importExtendedDataSquare(data [][]byte, codecType CodecType, cfg config) (*ExtendedDataSquare, error) {
+	if codec, ok := GetCodec(codecType); !ok {
 		return nil, errors.New("unsupported codecType")
 	} else {
-		if len(data) > 4*codec.maxChunks() {
+		if uint64(len(data)) > 4*uint64(codec.MaxChunks()) {
 			return nil, errors.New("number of chunks exceeds the maximum")
 		}
 	}
-	ds, err := newDataSquare(data)
+	ds, err := newConfiguredDataSquare(data, cfg)
 	if err != nil {
 		return nil, err
 	}
 
 	eds := ExtendedDataSquare{dataSquare: ds, codec: codecType}
 	if eds.width%2 != 0 {
+		eds.release()
 		return nil, errors.New("square width must be even")
 	}
 
@@ -61,15 +87,12 @@ func ImportExtendedDataSquare(data [][]byte, codecType CodecType) (*ExtendedData
 	return &eds, nil
 }
 
-func (eds *ExtendedDataSquare) erasureExtendSquare() error {
+func (eds *ExtendedDataSquare) erasureExtendSquare(ctx context.Context) error {
 	eds.originalDataWidth = eds.width
 	if err := eds.extendSquare(eds.width, bytes.Repeat([]byte{0}, int(eds.chunkSize))); err != nil {
 		return err
 	}
 
-	var shares [][]byte
-	var err error
-
 	// Extend original square horizontally and vertically
 	//  ------- -------
 	// |       |       |
@@ -80,13 +103,21 @@ func (eds *ExtendedDataSquare) erasureExtendSquare() error {
 	// |   E   |
 	// |       |
 	//  -------
-	for i := uint(0); i < eds.originalDataWidth; i++ {
+	err := parallelFor(eds.config.parallelism, eds.originalDataWidth, func(i uint) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		// Extend horizontally
-		shares, err = Encode(eds.rowSlice(i, 0, eds.originalDataWidth), eds.codec)
+		shares, err := Encode(eds.rowSlice(i, 0, eds.originalDataWidth), eds.codec)
 		if err != nil {
 			return err
 		}
-		if err := eds.setRowSlice(i, eds.originalDataWidth, shares); err != nil {
+		if err := eds.writeRowSlice(i, eds.originalDataWidth, shares); err != nil {
+			return err
+		}
+
+		if err := ctx.Err(); err != nil {
 			return err
 		}
 
@@ -95,12 +126,14 @@ func (eds *ExtendedDataSquare) erasureExtendSquare() error {
 		if err != nil {
 			return err
 		}
-		if err := eds.setColumnSlice(eds.originalDataWidth, i, shares); err != nil {
-			return err
-		}
+		return eds.writeColumnSlice(eds.originalDataWidth, i, shares)
+	})
+	if err != nil {
+		return err
 	}
 
-	// Extend extended square horizontally
+	// Extend extended square horizontally. This has to wait for the vertical
+	// extension above, as it reads the bottom left quadrant.
 	//  ------- -------
 	// |       |       |
 	// |   O   |   E   |
@@ -110,21 +143,40 @@ func (eds *ExtendedDataSquare) erasureExtendSquare() error {
 	// |   E → |   E   |
 	// |       |       |
 	//  ------- -------
-	for i := eds.originalDataWidth; i < eds.width; i++ {
-		// Extend horizontally
-		shares, err = Encode(eds.rowSlice(i, 0, eds.originalDataWidth), eds.codec)
-		if err != nil {
+	err = parallelFor(eds.config.parallelism, eds.originalDataWidth, func(i uint) error {
+		if err := ctx.Err(); err != nil {
 			return err
 		}
-		if err := eds.setRowSlice(i, eds.originalDataWidth, shares); err != nil {
+
+		// Extend horizontally
+		shares, err := Encode(eds.rowSlice(eds.originalDataWidth+i, 0, eds.originalDataWidth), eds.codec)
+		if err != nil {
 			return err
 		}
+		return eds.writeRowSlice(eds.originalDataWidth+i, eds.originalDataWidth, shares)
+	})
+	if err != nil {
+		return err
 	}
 
+	eds.resetRoots()
+
 	return nil
 }
 
+// Release returns the buffer backing the square to the pool set by
+// WithBufferPool. Neither the square nor any of its rows and columns may be
+// used afterwards.
+func (eds *ExtendedDataSquare) Release() {
+	eds.release()
+	eds.resetRoots()
+}
+
 func (eds *ExtendedDataSquare) deepCopy() (ExtendedDataSquare, error) {
-	eds, err := ImportExtendedDataSquare(eds.flattened(), eds.codec)
-	return *eds, err
+	imported, err := ImportExtendedDataSquare(eds.flattened(), eds.codec)
+	if err != nil {
+		return ExtendedDataSquare{}, err
+	}
+	imported.config = eds.config
+	return *imported, nil
 }
@@ -1,12 +1,13 @@
 package rsmt2d
 
 import (
+	"bytes"
 	"reflect"
 	"testing"
 )
 
 func TestComputeExtendedDataSquare(t *testing.T) {
-	codec := codecs[RSGF8].codecType()
+	codec := codecs[RSGF8].CodecType()
 	result, err := ComputeExtendedDataSquare([][]byte{
 		{1}, {2},
 		{3}, {4},
@@ -23,3 +24,136 @@ func TestComputeExtendedDataSquare(t *testing.T) {
 		t.Errorf("NewExtendedDataSquare failed for 2x2 square with chunk size 1")
 	}
 }
+
+func TestComputeExtendedDataSquareParallel(t *testing.T) {
+	for codec := range codecs {
+		for _, width := range []int{1, 2, 3, 8} {
+			data := make([][]byte, width*width)
+			for i := range data {
+				data[i] = bytes.Repeat([]byte{byte(i)}, 64)
+			}
+
+			sequential, err := ComputeExtendedDataSquare(data, codec, WithParallelism(1))
+			if err != nil {
+				t.Fatalf("sequential extension failed: %v", err)
+			}
+			parallel, err := ComputeExtendedDataSquare(data, codec, WithParallelism(8))
+			if err != nil {
+				t.Fatalf("parallel extension failed: %v", err)
+			}
+			if !reflect.DeepEqual(sequential.square, parallel.square) {
+				t.Errorf("codec %d: parallel extension of %dx%d square differs from sequential extension", codec, width, width)
+			}
+		}
+	}
+}
+
+func TestComputeExtendedDataSquareParallelError(t *testing.T) {
+	// Leopard rejects chunks that are not a multiple of 64 bytes.
+	data := make([][]byte, 16)
+	for i := range data {
+		data[i] = []byte{byte(i)}
+	}
+	if _, err := ComputeExtendedDataSquare(data, LeopardFF8, WithParallelism(4)); err == nil {
+		t.Errorf("parallel extension did not return the codec's error")
+	}
+}
+
+// sizedPool is a BufferPool keeping returned buffers by size.
+type sizedPool struct {
+	buffers map[int][][]byte
+	gets    int
+	reused  int
+	puts    int
+}
+
+func newSizedPool() *sizedPool {
+	return &sizedPool{buffers: make(map[int][][]byte)}
+}
+
+func (p *sizedPool) Get(size int) []byte {
+	p.gets++
+	if free := p.buffers[size]; len(free) > 0 {
+		p.reused++
+		p.buffers[size] = free[:len(free)-1]
+		return free[len(free)-1]
+	}
+	return make([]byte, size)
+}
+
+func (p *sizedPool) Put(buf []byte) {
+	p.puts++
+	p.buffers[cap(buf)] = append(p.buffers[cap(buf)], buf[:cap(buf)])
+}
+
+func TestBufferPool(t *testing.T) {
+	pool := newSizedPool()
+	data := [][]byte{{1, 2}, {3, 4}, {5, 6}, {7, 8}}
+	expected, err := ComputeExtendedDataSquare(data, RSGF8)
+	if err != nil {
+		panic(err)
+	}
+
+	eds, err := ComputeExtendedDataSquare(data, RSGF8, WithBufferPool(pool))
+	if err != nil {
+		panic(err)
+	}
+	if pool.gets != 2 || pool.puts != 1 {
+		t.Errorf("expected buffers for the original and extended square, got %d gets and %d puts", pool.gets, pool.puts)
+	}
+	if !reflect.DeepEqual(eds.flattened(), expected.flattened()) {
+		t.Errorf("square with buffer pool does not match")
+	}
+
+	flattened := eds.flattened()
+	flattened[0], flattened[5] = nil, nil
+	repaired, err := RepairExtendedDataSquare(expected.RowRoots(), expected.ColumnRoots(), flattened, RSGF8, WithBufferPool(pool))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	eds.Release()
+	repaired.Release()
+	if pool.puts != 3 {
+		t.Errorf("expected released squares to return their buffers, got %d puts", pool.puts)
+	}
+
+	imported, err := ImportExtendedDataSquare(expected.flattened(), RSGF8, WithBufferPool(pool))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pool.reused != 1 {
+		t.Errorf("expected the import to reuse a released buffer")
+	}
+	if !reflect.DeepEqual(imported.RowRoots(), expected.RowRoots()) {
+		t.Errorf("square on a reused buffer does not match")
+	}
+
+	_, err = ImportExtendedDataSquare(expected.flattened(), RSGF8, WithBufferPool(pool), WithExpectedRoots(expected.ColumnRoots(), expected.RowRoots()))
+	if err == nil {
+		t.Errorf("expected roots mismatch")
+	}
+	if pool.puts != 4 {
+		t.Errorf("failed imports should return their buffers")
+	}
+}
+
+// shortPool is a BufferPool returning buffers that are too small.
+type shortPool struct{}
+
+func (shortPool) Get(size int) []byte { return make([]byte, size/2) }
+func (shortPool) Put(buf []byte)      {}
+
+func TestBufferPoolShortBuffers(t *testing.T) {
+	data := [][]byte{{1, 2}, {3, 4}, {5, 6}, {7, 8}}
+	expected, err := ComputeExtendedDataSquare(data, RSGF8)
+	if err != nil {
+		panic(err)
+	}
+	eds, err := ComputeExtendedDataSquare(data, RSGF8, WithBufferPool(shortPool{}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(eds.flattened(), expected.flattened()) {
+		t.Errorf("square with short buffers does not match")
+	}
+}
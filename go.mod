@@ -14,6 +14,5 @@ require (
 	github.com/vivint/infectious v0.0.0-20190108171102-2455b059135b
 	golang.org/x/net v0.0.0-20200520182314-0ba52f642ac2 // indirect
 	golang.org/x/sys v0.0.0-20200519105757-fe76b779f299 // indirect
-	gonum.org/v1/gonum v0.7.0
 	gopkg.in/yaml.v2 v2.3.0 // indirect
 )
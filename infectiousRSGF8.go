@@ -4,32 +4,37 @@ import (
 	"github.com/vivint/infectious"
 )
 
-var _ Codec = &rsGF8Codec{}
+var _ CachingCodec = &rsGF8Codec{}
 
 func init() {
-	registerCodec(RSGF8, newRSGF8Codec())
+	RegisterCodec(RSGF8, newRSGF8Codec())
 }
 
+// rsGF8Codec is safe for concurrent use: an infectious.FEC is never modified
+// after creation, and the cache holding them is synchronized.
 type rsGF8Codec struct {
-	infectiousCache map[int]*infectious.FEC
+	infectiousCache *lruCache
 }
 
 func newRSGF8Codec() *rsGF8Codec {
-	return &rsGF8Codec{make(map[int]*infectious.FEC)}
+	return &rsGF8Codec{newLRUCache(DefaultCacheSize, func(k interface{}) (interface{}, error) {
+		return infectious.NewFEC(k.(int), k.(int)*2)
+	})}
 }
 
-func (c *rsGF8Codec) encode(data [][]byte) ([][]byte, error) {
-	var fec *infectious.FEC
-	var err error
-	if value, ok := c.infectiousCache[len(data)]; ok {
-		fec = value
-	} else {
-		fec, err = infectious.NewFEC(len(data), len(data)*2)
-		if err != nil {
-			return nil, err
-		}
+// fec returns the cached FEC for k data shares, creating it if needed.
+func (c *rsGF8Codec) fec(k int) (*infectious.FEC, error) {
+	value, err := c.infectiousCache.get(k)
+	if err != nil {
+		return nil, err
+	}
+	return value.(*infectious.FEC), nil
+}
 
-		c.infectiousCache[len(data)] = fec
+func (c *rsGF8Codec) Encode(data [][]byte) ([][]byte, error) {
+	fec, err := c.fec(len(data))
+	if err != nil {
+		return nil, err
 	}
 
 	shares := make([][]byte, len(data))
@@ -46,18 +51,10 @@ func (c *rsGF8Codec) encode(data [][]byte) ([][]byte, error) {
 
 	return shares, err
 }
-func (c *rsGF8Codec) decode(data [][]byte) ([][]byte, error) {
-	var fec *infectious.FEC
-	var err error
-	if value, ok := c.infectiousCache[len(data)/2]; ok {
-		fec = value
-	} else {
-		fec, err = infectious.NewFEC(len(data)/2, len(data))
-		if err != nil {
-			return nil, err
-		}
-
-		c.infectiousCache[len(data)/2] = fec
+func (c *rsGF8Codec) Decode(data [][]byte) ([][]byte, error) {
+	fec, err := c.fec(len(data) / 2)
+	if err != nil {
+		return nil, err
 	}
 
 	rebuiltShares := make([][]byte, len(data)/2)
@@ -76,11 +73,26 @@ func (c *rsGF8Codec) decode(data [][]byte) ([][]byte, error) {
 	return rebuiltShares, err
 }
 
-func (c *rsGF8Codec) codecType() CodecType {
+func (c *rsGF8Codec) CodecType() CodecType {
 	return RSGF8
 }
 
-// maxChunks returns the max. number of chunks each code supports in a 2D square.
-func (c *rsGF8Codec) maxChunks() int {
+// MaxChunks returns the max. number of chunks each code supports in a 2D square.
+func (c *rsGF8Codec) MaxChunks() int {
 	return 128 * 128
 }
+
+// WarmCache creates the FECs for the given original data widths.
+func (c *rsGF8Codec) WarmCache(widths ...int) error {
+	return c.infectiousCache.warm(widths...)
+}
+
+// SetCacheSize bounds the number of cached FECs.
+func (c *rsGF8Codec) SetCacheSize(size int) {
+	c.infectiousCache.setSize(size)
+}
+
+// CacheStats returns the usage counters of the FEC cache.
+func (c *rsGF8Codec) CacheStats() CacheStats {
+	return c.infectiousCache.cacheStats()
+}
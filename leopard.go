@@ -11,26 +11,26 @@ var _ Codec = leoRSFF8Codec{}
 var _ Codec = leoRSFF16Codec{}
 
 func init() {
-	registerCodec(LeopardFF8, newLeoRSFF8Codec())
-	registerCodec(LeopardFF16, newLeoRSFF16Codec())
+	RegisterCodec(LeopardFF8, newLeoRSFF8Codec())
+	RegisterCodec(LeopardFF16, newLeoRSFF16Codec())
 }
 
 type leoRSFF8Codec struct{}
 
-func (l leoRSFF8Codec) encode(data [][]byte) ([][]byte, error) {
+func (l leoRSFF8Codec) Encode(data [][]byte) ([][]byte, error) {
 	return leopard.Encode(data)
 }
 
-func (l leoRSFF8Codec) decode(data [][]byte) ([][]byte, error) {
+func (l leoRSFF8Codec) Decode(data [][]byte) ([][]byte, error) {
 	half := len(data) / 2
 	return leopard.Decode(data[:half], data[half:])
 }
 
-func (l leoRSFF8Codec) codecType() CodecType {
+func (l leoRSFF8Codec) CodecType() CodecType {
 	return LeopardFF8
 }
 
-func (l leoRSFF8Codec) maxChunks() int {
+func (l leoRSFF8Codec) MaxChunks() int {
 	return 128 * 128
 }
 
@@ -40,20 +40,20 @@ func newLeoRSFF8Codec() leoRSFF8Codec {
 
 type leoRSFF16Codec struct{}
 
-func (leo leoRSFF16Codec) encode(data [][]byte) ([][]byte, error) {
+func (leo leoRSFF16Codec) Encode(data [][]byte) ([][]byte, error) {
 	return leopard.Encode(data)
 }
 
-func (leo leoRSFF16Codec) decode(data [][]byte) ([][]byte, error) {
+func (leo leoRSFF16Codec) Decode(data [][]byte) ([][]byte, error) {
 	half := len(data) / 2
 	return leopard.Decode(data[:half], data[half:])
 }
 
-func (leo leoRSFF16Codec) codecType() CodecType {
+func (leo leoRSFF16Codec) CodecType() CodecType {
 	return LeopardFF16
 }
 
-func (leo leoRSFF16Codec) maxChunks() int {
+func (leo leoRSFF16Codec) MaxChunks() int {
 	return 32768 * 32768
 }
 
@@ -0,0 +1,57 @@
+//go:build leopard
+// +build leopard
+
+package rsmt2d
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/lazyledger/go-leopard"
+)
+
+// TestLeopardMatchesCgo checks that the pure-Go codecs produce the same parity
+// and recover the same data as go-leopard.
+func TestLeopardMatchesCgo(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, count := range []int{1, 2, 3, 4, 5, 64, 100, 128, 129, 200, 256, 1000} {
+		data := make([][]byte, count)
+		for i := range data {
+			data[i] = make([]byte, 128)
+			rnd.Read(data[i])
+		}
+
+		expected, err := leopard.Encode(data)
+		if err != nil {
+			t.Fatalf("go-leopard failed to encode %d chunks: %v", count, err)
+		}
+		parity, err := leopardEncode(data)
+		if err != nil {
+			t.Fatalf("failed to encode %d chunks: %v", count, err)
+		}
+		for i := range parity {
+			if !bytes.Equal(parity[i], expected[i]) {
+				t.Fatalf("parity chunk %d of %d differs from go-leopard", i, count)
+			}
+		}
+
+		shares := append(append([][]byte{}, data...), parity...)
+		for _, i := range rnd.Perm(2 * count)[:count] {
+			shares[i] = nil
+		}
+		expected, err = leopard.Decode(shares[:count], shares[count:])
+		if err != nil {
+			t.Fatalf("go-leopard failed to decode %d chunks: %v", count, err)
+		}
+		rebuilt, err := leopardDecode(shares)
+		if err != nil {
+			t.Fatalf("failed to decode %d chunks: %v", count, err)
+		}
+		for i := range rebuilt {
+			if !bytes.Equal(rebuilt[i], expected[i]) {
+				t.Fatalf("decoded chunk %d of %d differs from go-leopard", i, count)
+			}
+		}
+	}
+}
@@ -0,0 +1,449 @@
+package rsmt2d
+
+import (
+	"errors"
+	"sync"
+)
+
+// This file is a pure-Go port of the Leopard-RS algorithms
+// (https://github.com/catid/leopard). It produces the same parity data as the
+// C library, so squares extended with either implementation are identical.
+//
+// Buffers are processed as vectors of field elements. In the 8-bit field every
+// byte is one element. In the 16-bit field the buffer is split into 64 byte
+// blocks, where the first 32 bytes hold the low bytes and the last 32 bytes
+// hold the high bytes of 32 elements.
+
+const leoBufferAlignment = 64
+
+var errLeoInvalidSize = errors.New("buffer size must be a multiple of 64 bytes")
+
+// leopardEncode mirrors leo_encode, which picks the 8-bit field whenever the
+// total number of chunks fits into it.
+func leopardEncode(data [][]byte) ([][]byte, error) {
+	if err := leopardCheckSize(data); err != nil {
+		return nil, err
+	}
+	return leoFieldFor(len(data), len(data)).encode(data), nil
+}
+
+func leopardDecode(data [][]byte) ([][]byte, error) {
+	if err := leopardCheckSize(data); err != nil {
+		return nil, err
+	}
+	half := len(data) / 2
+	return leoFieldFor(half, half).decode(data)
+}
+
+func leopardCheckSize(data [][]byte) error {
+	for _, d := range data {
+		if d != nil && len(d)%leoBufferAlignment != 0 {
+			return errLeoInvalidSize
+		}
+	}
+	return nil
+}
+
+// leoField holds the lookup tables for one of the finite fields used by Leopard.
+// The tables are built once on first use and are read-only afterwards.
+type leoField struct {
+	bits        uint
+	order       int
+	modulus     uint16
+	polynomial  int
+	cantorBasis []uint16
+
+	once     sync.Once
+	logLUT   []uint16
+	expLUT   []uint16
+	fftSkew  []uint16
+	logWalsh []uint16
+}
+
+var (
+	leoFF8 = newLeoField(8, 0x11D, []uint16{
+		1, 214, 152, 146, 86, 200, 88, 230,
+	})
+	leoFF16 = newLeoField(16, 0x1002D, []uint16{
+		0x0001, 0xACCA, 0x3C0E, 0x163E,
+		0xC582, 0xED2E, 0x914C, 0x4012,
+		0x6C98, 0x10D8, 0x6A72, 0xB900,
+		0xFDB8, 0xFB34, 0xFF38, 0x991E,
+	})
+)
+
+func newLeoField(bits uint, polynomial int, cantorBasis []uint16) *leoField {
+	return &leoField{
+		bits:        bits,
+		order:       1 << bits,
+		modulus:     uint16(1<<bits - 1),
+		polynomial:  polynomial,
+		cantorBasis: cantorBasis,
+	}
+}
+
+// leoFieldFor returns the field Leopard uses for the given number of
+// original and recovery chunks.
+func leoFieldFor(originalCount, recoveryCount int) *leoField {
+	if originalCount+recoveryCount <= leoFF8.order {
+		return leoFF8
+	}
+	return leoFF16
+}
+
+func (f *leoField) init() {
+	f.once.Do(func() {
+		f.initLUTs()
+		f.initFFTSkew()
+	})
+}
+
+func (f *leoField) initLUTs() {
+	f.expLUT = make([]uint16, f.order)
+	f.logLUT = make([]uint16, f.order)
+
+	// LFSR table generation:
+	state := 1
+	for i := uint16(0); i < f.modulus; i++ {
+		f.expLUT[state] = i
+		state <<= 1
+		if state >= f.order {
+			state ^= f.polynomial
+		}
+	}
+	f.expLUT[0] = f.modulus
+
+	// Conversion to Cantor basis:
+	f.logLUT[0] = 0
+	for i := uint(0); i < f.bits; i++ {
+		basis := f.cantorBasis[i]
+		width := 1 << i
+		for j := 0; j < width; j++ {
+			f.logLUT[j+width] = f.logLUT[j] ^ basis
+		}
+	}
+
+	for i := 0; i < f.order; i++ {
+		f.logLUT[i] = f.expLUT[f.logLUT[i]]
+	}
+
+	for i := 0; i < f.order; i++ {
+		f.expLUT[f.logLUT[i]] = uint16(i)
+	}
+
+	f.expLUT[f.modulus] = f.expLUT[0]
+}
+
+func (f *leoField) initFFTSkew() {
+	temp := make([]uint16, f.bits-1)
+
+	// Generate FFT skew vector {1}:
+	for i := uint(1); i < f.bits; i++ {
+		temp[i-1] = uint16(1 << i)
+	}
+
+	f.fftSkew = make([]uint16, f.modulus)
+	for m := uint(0); m < f.bits-1; m++ {
+		step := 1 << (m + 1)
+
+		f.fftSkew[1<<m-1] = 0
+
+		for i := m; i < f.bits-1; i++ {
+			s := 1 << (i + 1)
+			for j := 1<<m - 1; j < s; j += step {
+				f.fftSkew[j+s] = f.fftSkew[j] ^ temp[i]
+			}
+		}
+
+		temp[m] = f.modulus - f.logLUT[f.mulLog(temp[m], f.logLUT[temp[m]^1])]
+
+		for i := m + 1; i < f.bits-1; i++ {
+			sum := f.addMod(f.logLUT[temp[i]^1], temp[m])
+			temp[i] = f.mulLog(temp[i], sum)
+		}
+	}
+
+	for i := range f.fftSkew {
+		f.fftSkew[i] = f.logLUT[f.fftSkew[i]]
+	}
+
+	// Precalculate FWHT(Log[i]):
+	f.logWalsh = make([]uint16, f.order)
+	copy(f.logWalsh, f.logLUT)
+	f.logWalsh[0] = 0
+	f.fwht(f.logWalsh, f.order, f.order)
+}
+
+// addMod returns a + b modulo the field modulus. The reduction is partial, so
+// the modulus itself may be returned in place of zero.
+func (f *leoField) addMod(a, b uint16) uint16 {
+	sum := uint32(a) + uint32(b)
+	return uint16(sum+sum>>f.bits) & f.modulus
+}
+
+// subMod returns a - b modulo the field modulus, with the same partial
+// reduction as addMod.
+func (f *leoField) subMod(a, b uint16) uint16 {
+	dif := uint32(a) - uint32(b)
+	return uint16(dif+dif>>f.bits) & f.modulus
+}
+
+// mulLog returns a * exp(logB).
+func (f *leoField) mulLog(a, logB uint16) uint16 {
+	if a == 0 {
+		return 0
+	}
+	return f.expLUT[f.addMod(f.logLUT[a], logB)]
+}
+
+// fwht computes the Walsh-Hadamard transform of data modulo the field
+// modulus. Only the first mTruncated entries of data may be non-zero.
+func (f *leoField) fwht(data []uint16, m, mTruncated int) {
+	for dist := 1; dist < m; dist <<= 1 {
+		for r := 0; r < mTruncated; r += dist * 2 {
+			for i := r; i < r+dist; i++ {
+				a, b := data[i], data[i+dist]
+				data[i], data[i+dist] = f.addMod(a, b), f.subMod(a, b)
+			}
+		}
+	}
+}
+
+// mulMem sets x = y * exp(logM).
+func (f *leoField) mulMem(x, y []byte, logM uint16) {
+	if f.bits == 8 {
+		var lut [256]byte
+		for i := range lut {
+			lut[i] = byte(f.mulLog(uint16(i), logM))
+		}
+		for i, v := range y {
+			x[i] = lut[v]
+		}
+		return
+	}
+
+	for block := 0; block < len(y); block += leoBufferAlignment {
+		for j := block; j < block+leoBufferAlignment/2; j++ {
+			prod := f.mulLog(uint16(y[j])|uint16(y[j+32])<<8, logM)
+			x[j] = byte(prod)
+			x[j+32] = byte(prod >> 8)
+		}
+	}
+}
+
+// mulAddMem sets x ^= y * exp(logM).
+func (f *leoField) mulAddMem(x, y []byte, logM uint16) {
+	if f.bits == 8 {
+		var lut [256]byte
+		for i := range lut {
+			lut[i] = byte(f.mulLog(uint16(i), logM))
+		}
+		for i, v := range y {
+			x[i] ^= lut[v]
+		}
+		return
+	}
+
+	for block := 0; block < len(y); block += leoBufferAlignment {
+		for j := block; j < block+leoBufferAlignment/2; j++ {
+			prod := f.mulLog(uint16(y[j])|uint16(y[j+32])<<8, logM)
+			x[j] ^= byte(prod)
+			x[j+32] ^= byte(prod >> 8)
+		}
+	}
+}
+
+func xorMem(x, y []byte) {
+	for i, v := range y {
+		x[i] ^= v
+	}
+}
+
+// ifftDIT computes the inverse FFT of work in place, decimating in time.
+// Entries of work from mTruncated onwards must be zero. The skew factors are
+// read from fftSkew starting at skewOffset.
+func (f *leoField) ifftDIT(work [][]byte, mTruncated, m, skewOffset int) {
+	for dist := 1; dist < m; dist <<= 1 {
+		for r := 0; r < mTruncated; r += dist * 2 {
+			logM := f.fftSkew[skewOffset+r+dist]
+			for i := r; i < r+dist; i++ {
+				// y ^= x, x ^= y * m
+				xorMem(work[i+dist], work[i])
+				if logM != f.modulus {
+					f.mulAddMem(work[i], work[i+dist], logM)
+				}
+			}
+		}
+	}
+}
+
+// fftDIT computes the FFT of work in place, decimating in time. Only the
+// first mTruncated outputs are computed.
+func (f *leoField) fftDIT(work [][]byte, mTruncated, m, skewOffset int) {
+	for dist := m >> 1; dist > 0; dist >>= 1 {
+		for r := 0; r < mTruncated; r += dist * 2 {
+			logM := f.fftSkew[skewOffset+r+dist]
+			for i := r; i < r+dist; i++ {
+				// x ^= y * m, y ^= x
+				if logM != f.modulus {
+					f.mulAddMem(work[i], work[i+dist], logM)
+				}
+				xorMem(work[i+dist], work[i])
+			}
+		}
+	}
+}
+
+// encode returns len(data) recovery chunks for data.
+func (f *leoField) encode(data [][]byte) [][]byte {
+	f.init()
+
+	count := len(data)
+	size := len(data[0])
+
+	// Handle k = 1 case
+	if count == 1 {
+		chunk := make([]byte, size)
+		copy(chunk, data[0])
+		return [][]byte{chunk}
+	}
+
+	// As there are as many recovery chunks as original chunks, a single set
+	// of m >= count original chunks is transformed.
+	m := ceilPow2(count)
+	work := make([][]byte, m)
+	for i := range work {
+		work[i] = make([]byte, size)
+		if i < count {
+			copy(work[i], data[i])
+		}
+	}
+
+	// work <- IFFT(data, m, m)
+	f.ifftDIT(work, count, m, m-1)
+
+	// work <- FFT(work, m, 0)
+	f.fftDIT(work, count, m, -1)
+
+	return work[:count]
+}
+
+// decode takes original chunks followed by the same number of recovery
+// chunks, with missing chunks set to nil, and returns the original chunks.
+func (f *leoField) decode(shares [][]byte) ([][]byte, error) {
+	f.init()
+
+	count := len(shares) / 2
+	original := shares[:count]
+	recovery := shares[count:]
+
+	var size, available int
+	for _, share := range shares {
+		if share != nil {
+			size = len(share)
+			available++
+		}
+	}
+	if available < count {
+		return nil, errors.New("not enough recovery data received")
+	}
+
+	rebuilt := make([][]byte, count)
+	var missing bool
+	for i, share := range original {
+		if share == nil {
+			missing = true
+			continue
+		}
+		rebuilt[i] = make([]byte, size)
+		copy(rebuilt[i], share)
+	}
+	if !missing {
+		return rebuilt, nil
+	}
+
+	// Handle k = 1 case
+	if count == 1 {
+		rebuilt[0] = make([]byte, size)
+		copy(rebuilt[0], recovery[0])
+		return rebuilt, nil
+	}
+
+	m := ceilPow2(count)
+	n := ceilPow2(m + count)
+
+	// Fill in error locations
+	errLocs := make([]uint16, f.order)
+	for i, share := range recovery {
+		if share == nil {
+			errLocs[i] = 1
+		}
+	}
+	for i := count; i < m; i++ {
+		errLocs[i] = 1
+	}
+	for i, share := range original {
+		if share == nil {
+			errLocs[i+m] = 1
+		}
+	}
+
+	// Evaluate error locator polynomial
+	f.fwht(errLocs, f.order, m+count)
+	for i := range errLocs {
+		errLocs[i] = uint16((uint32(errLocs[i]) * uint32(f.logWalsh[i])) % uint32(f.modulus))
+	}
+	f.fwht(errLocs, f.order, f.order)
+
+	work := make([][]byte, n)
+	for i := range work {
+		work[i] = make([]byte, size)
+	}
+
+	// work <- recovery data
+	for i, share := range recovery {
+		if share != nil {
+			f.mulMem(work[i], share, errLocs[i])
+		}
+	}
+
+	// work <- original data
+	for i, share := range original {
+		if share != nil {
+			f.mulMem(work[m+i], share, errLocs[m+i])
+		}
+	}
+
+	// work <- IFFT(work, n, 0)
+	f.ifftDIT(work, m+count, n, -1)
+
+	// work <- FormalDerivative(work, n)
+	for i := 1; i < n; i++ {
+		width := ((i ^ (i - 1)) + 1) >> 1
+		for j := 0; j < width; j++ {
+			xorMem(work[i-width+j], work[i+j])
+		}
+	}
+
+	// work <- FFT(work, n, 0) truncated to m + count
+	f.fftDIT(work, m+count, n, -1)
+
+	// Reveal erasures
+	for i, share := range original {
+		if share == nil {
+			rebuilt[i] = make([]byte, size)
+			f.mulMem(rebuilt[i], work[i+m], f.modulus-errLocs[i+m])
+		}
+	}
+
+	return rebuilt, nil
+}
+
+// ceilPow2 returns the smallest power of two that is at least n.
+func ceilPow2(n int) int {
+	p := 1
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
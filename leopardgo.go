@@ -0,0 +1,58 @@
+// +build !leopard
+
+package rsmt2d
+
+// Pure-Go Leopard codecs, used when the cgo bindings in leopard.go are not
+// built in. Both codecs produce the same output as go-leopard.
+
+var _ Codec = leoRSFF8Codec{}
+var _ Codec = leoRSFF16Codec{}
+
+func init() {
+	RegisterCodec(LeopardFF8, newLeoRSFF8Codec())
+	RegisterCodec(LeopardFF16, newLeoRSFF16Codec())
+}
+
+type leoRSFF8Codec struct{}
+
+func (l leoRSFF8Codec) Encode(data [][]byte) ([][]byte, error) {
+	return leopardEncode(data)
+}
+
+func (l leoRSFF8Codec) Decode(data [][]byte) ([][]byte, error) {
+	return leopardDecode(data)
+}
+
+func (l leoRSFF8Codec) CodecType() CodecType {
+	return LeopardFF8
+}
+
+func (l leoRSFF8Codec) MaxChunks() int {
+	return 128 * 128
+}
+
+func newLeoRSFF8Codec() leoRSFF8Codec {
+	return leoRSFF8Codec{}
+}
+
+type leoRSFF16Codec struct{}
+
+func (leo leoRSFF16Codec) Encode(data [][]byte) ([][]byte, error) {
+	return leopardEncode(data)
+}
+
+func (leo leoRSFF16Codec) Decode(data [][]byte) ([][]byte, error) {
+	return leopardDecode(data)
+}
+
+func (leo leoRSFF16Codec) CodecType() CodecType {
+	return LeopardFF16
+}
+
+func (leo leoRSFF16Codec) MaxChunks() int {
+	return 32768 * 32768
+}
+
+func newLeoRSFF16Codec() leoRSFF16Codec {
+	return leoRSFF16Codec{}
+}
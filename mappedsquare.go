@@ -0,0 +1,275 @@
+package rsmt2d
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+)
+
+// Mapped squares are stored in the format written by WriteTo, followed by
+// the roots:
+//
+//	rootSize    uint32
+//	rowRoots    [width][rootSize]byte
+//	columnRoots [width][rootSize]byte
+//
+// so that opening a square does not require reading all of its chunks.
+
+// MappedExtendedDataSquare is a read-only extended data square backed by a
+// memory-mapped file. Its chunks are only read from disk when accessed, and
+// the trees of its rows and columns are checked against the stored roots when
+// they are first built. After Close, its methods return an error, or panic if
+// they cannot.
+type MappedExtendedDataSquare struct {
+	eds     *ExtendedDataSquare
+	mapping []byte
+}
+
+// CreateMappedExtendedDataSquare writes eds to a new file at path and returns
+// it memory-mapped, with the configuration of eds.
+func CreateMappedExtendedDataSquare(path string, eds *ExtendedDataSquare) (*MappedExtendedDataSquare, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeMappedExtendedDataSquare(f, eds); err != nil {
+		f.Close()
+		os.Remove(path)
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+
+	mapped, err := OpenMappedExtendedDataSquare(path)
+	if err != nil {
+		return nil, err
+	}
+	mapped.eds.config = eds.config
+	return mapped, nil
+}
+
+func writeMappedExtendedDataSquare(f *os.File, eds *ExtendedDataSquare) error {
+	w := bufio.NewWriter(f)
+	if _, err := eds.WriteTo(w); err != nil {
+		return err
+	}
+
+	rowRoots, columnRoots := eds.RowRoots(), eds.ColumnRoots()
+	rootSize := make([]byte, 4)
+	binary.BigEndian.PutUint32(rootSize, uint32(len(rowRoots[0])))
+	if _, err := w.Write(rootSize); err != nil {
+		return err
+	}
+	for _, roots := range [][][]byte{rowRoots, columnRoots} {
+		for _, root := range roots {
+			if len(root) != len(rowRoots[0]) {
+				return errors.New("roots must all have the same size")
+			}
+			if _, err := w.Write(root); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// OpenMappedExtendedDataSquare memory-maps the square stored at path by
+// CreateMappedExtendedDataSquare. The header is checked, and the stored roots
+// against the roots hash in the header. The chunks are only checked against
+// the roots when a proof of their row or column is computed. The options are
+// used for computing proofs and must match those the stored roots were
+// computed with.
+func OpenMappedExtendedDataSquare(path string, opts ...Option) (*MappedExtendedDataSquare, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() < headerSize {
+		return nil, errors.New("file is truncated")
+	}
+	if info.Size() > int64(maxInt) {
+		return nil, errors.New("file is too large")
+	}
+
+	mapping, err := mmapFile(f, int(info.Size()))
+	if err != nil {
+		return nil, err
+	}
+	eds, err := parseMappedExtendedDataSquare(mapping, opts)
+	if err != nil {
+		munmap(mapping)
+		return nil, err
+	}
+
+	return &MappedExtendedDataSquare{eds: eds, mapping: mapping}, nil
+}
+
+func parseMappedExtendedDataSquare(mapping []byte, opts []Option) (*ExtendedDataSquare, error) {
+	var header serializationHeader
+	if err := header.unmarshal(mapping[:headerSize]); err != nil {
+		return nil, err
+	}
+
+	dataEnd := headerSize + header.dataSize()
+	if len(mapping) < dataEnd+4 {
+		return nil, errors.New("file is truncated")
+	}
+	rootSize := uint64(binary.BigEndian.Uint32(mapping[dataEnd:]))
+	if rootSize == 0 || uint64(len(mapping)-dataEnd-4) != 2*uint64(header.width)*rootSize {
+		return nil, errors.New("file size does not match the header")
+	}
+
+	roots := make([][]byte, 2*header.width)
+	for i := range roots {
+		start := dataEnd + 4 + i*int(rootSize)
+		roots[i] = append([]byte(nil), mapping[start:start+int(rootSize)]...)
+	}
+	rowRoots, columnRoots := roots[:header.width], roots[header.width:]
+	if rootsHash(rowRoots, columnRoots) != header.rootsHash {
+		return nil, errors.New("roots do not match the header")
+	}
+
+	ds := newDataSquareFromBuffer(mapping[headerSize:dataEnd:dataEnd], uint(header.width), uint(header.chunkSize), newConfig(opts))
+	ds.loadRoots(rowRoots, columnRoots)
+	return &ExtendedDataSquare{
+		dataSquare:        ds,
+		originalDataWidth: ds.width / 2,
+		codec:             header.codec,
+	}, nil
+}
+
+// errMappedSquareClosed is returned by the methods of a closed mapped square
+// that can return errors. The other methods panic.
+var errMappedSquareClosed = errors.New("mapped square is closed")
+
+// Close unmaps the file.
+func (m *MappedExtendedDataSquare) Close() error {
+	if m.mapping == nil {
+		return errMappedSquareClosed
+	}
+	err := munmap(m.mapping)
+	m.mapping = nil
+	m.eds = nil
+	return err
+}
+
+// square returns the square, and panics once it is closed rather than reading
+// unmapped memory.
+func (m *MappedExtendedDataSquare) square() *ExtendedDataSquare {
+	if m.eds == nil {
+		panic("rsmt2d: mapped square used after Close")
+	}
+	return m.eds
+}
+
+// Width returns the width of the square.
+func (m *MappedExtendedDataSquare) Width() uint {
+	return m.square().Width()
+}
+
+// Row returns a copy of the chunks of a row.
+func (m *MappedExtendedDataSquare) Row(x uint) [][]byte {
+	return copyChunks(m.square().Row(x))
+}
+
+// Column returns a copy of the chunks of a column.
+func (m *MappedExtendedDataSquare) Column(y uint) [][]byte {
+	return copyChunks(m.square().Column(y))
+}
+
+// Cell returns a copy of a single chunk.
+func (m *MappedExtendedDataSquare) Cell(x uint, y uint) []byte {
+	return m.square().Cell(x, y)
+}
+
+// RowRoots returns the stored roots of all rows.
+func (m *MappedExtendedDataSquare) RowRoots() [][]byte {
+	return m.square().RowRoots()
+}
+
+// ColumnRoots returns the stored roots of all columns.
+func (m *MappedExtendedDataSquare) ColumnRoots() [][]byte {
+	return m.square().ColumnRoots()
+}
+
+// ProveRowShare is ExtendedDataSquare.ProveRowShare.
+func (m *MappedExtendedDataSquare) ProveRowShare(row uint, column uint) (*ShareProof, error) {
+	if m.eds == nil {
+		return nil, errMappedSquareClosed
+	}
+	return m.eds.ProveRowShare(row, column)
+}
+
+// ProveColumnShare is ExtendedDataSquare.ProveColumnShare.
+func (m *MappedExtendedDataSquare) ProveColumnShare(row uint, column uint) (*ShareProof, error) {
+	if m.eds == nil {
+		return nil, errMappedSquareClosed
+	}
+	return m.eds.ProveColumnShare(row, column)
+}
+
+// ProveRowRange is ExtendedDataSquare.ProveRowRange.
+func (m *MappedExtendedDataSquare) ProveRowRange(row uint, start uint, end uint) (*RangeProof, error) {
+	if m.eds == nil {
+		return nil, errMappedSquareClosed
+	}
+	return m.eds.ProveRowRange(row, start, end)
+}
+
+// ProveColumnRange is ExtendedDataSquare.ProveColumnRange.
+func (m *MappedExtendedDataSquare) ProveColumnRange(column uint, start uint, end uint) (*RangeProof, error) {
+	if m.eds == nil {
+		return nil, errMappedSquareClosed
+	}
+	return m.eds.ProveColumnRange(column, start, end)
+}
+
+// ProveNamespace is ExtendedDataSquare.ProveNamespace.
+func (m *MappedExtendedDataSquare) ProveNamespace(axis Axis, index uint, nID []byte) ([][]byte, *NamespaceProof, error) {
+	if m.eds == nil {
+		return nil, nil, errMappedSquareClosed
+	}
+	return m.eds.ProveNamespace(axis, index, nID)
+}
+
+// WriteTo is ExtendedDataSquare.WriteTo.
+func (m *MappedExtendedDataSquare) WriteTo(w io.Writer) (int64, error) {
+	if m.eds == nil {
+		return 0, errMappedSquareClosed
+	}
+	return m.eds.WriteTo(w)
+}
+
+// Copy returns a copy of the square in memory, which can be modified.
+func (m *MappedExtendedDataSquare) Copy() (*ExtendedDataSquare, error) {
+	if m.eds == nil {
+		return nil, errMappedSquareClosed
+	}
+	eds, err := m.eds.deepCopy()
+	if err != nil {
+		return nil, err
+	}
+	return &eds, nil
+}
+
+func copyChunks(chunks [][]byte) [][]byte {
+	copied := make([][]byte, len(chunks))
+	for i, chunk := range chunks {
+		copied[i] = append([]byte(nil), chunk...)
+	}
+	return copied
+}
@@ -0,0 +1,139 @@
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package rsmt2d
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "rsmt2d")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return dir
+}
+
+func TestMappedExtendedDataSquare(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "square")
+
+	eds, err := ComputeExtendedDataSquare([][]byte{
+		{1, 2}, {3, 4}, {5, 6},
+		{7, 8}, {9, 10}, {11, 12},
+		{13, 14}, {15, 16}, {17, 18},
+	}, RSGF8, WithHasher(sha512.New))
+	if err != nil {
+		panic(err)
+	}
+
+	created, err := CreateMappedExtendedDataSquare(path, eds)
+	assert.NoError(t, err)
+	assert.NoError(t, created.Close())
+	assert.Error(t, created.Close())
+	assert.Panics(t, func() { created.Row(0) })
+	assert.Panics(t, func() { created.Cell(0, 0) })
+	_, err = created.ProveRowShare(0, 0)
+	assert.Error(t, err)
+	_, err = created.Copy()
+	assert.Error(t, err)
+	_, err = created.WriteTo(ioutil.Discard)
+	assert.Error(t, err)
+	_, err = CreateMappedExtendedDataSquare(path, eds)
+	assert.Error(t, err, "existing files must not be overwritten")
+
+	mapped, err := OpenMappedExtendedDataSquare(path, WithHasher(sha512.New))
+	assert.NoError(t, err)
+	defer mapped.Close()
+
+	assert.Equal(t, &mapped.mapping[headerSize], &mapped.eds.buf[0], "chunks must be served from the mapping")
+	assert.Equal(t, eds.RowRoots(), mapped.RowRoots())
+	assert.Equal(t, eds.ColumnRoots(), mapped.ColumnRoots())
+	assert.Equal(t, eds.originalDataWidth, mapped.eds.originalDataWidth)
+	assert.Equal(t, eds.codec, mapped.eds.codec)
+	for i := uint(0); i < eds.Width(); i++ {
+		assert.Equal(t, eds.Row(i), mapped.Row(i))
+		assert.Equal(t, eds.Column(i), mapped.Column(i))
+	}
+	assert.Equal(t, eds.Cell(4, 5), mapped.Cell(4, 5))
+
+	proof, err := mapped.ProveRowShare(4, 5)
+	assert.NoError(t, err)
+	assert.True(t, VerifyShareProof(eds.RowRoots()[4], proof, sha512.New))
+	assert.NotNil(t, mapped.eds.rowTrees[4])
+	assert.Nil(t, mapped.eds.rowTrees[3], "only the trees of proven rows should be built")
+
+	row := mapped.Row(4)
+	row[0][0]++
+	assert.Equal(t, eds.Row(4), mapped.Row(4), "rows must be copies of the mapping")
+
+	copied, err := mapped.Copy()
+	assert.NoError(t, err)
+	assert.NoError(t, copied.setCell(0, 0, []byte{42, 42}))
+	assert.Equal(t, eds.Cell(0, 0), mapped.Cell(0, 0))
+	assert.Equal(t, []byte{42, 42}, copied.Cell(0, 0))
+}
+
+func TestOpenMappedExtendedDataSquareInvalid(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "square")
+
+	eds, err := ComputeExtendedDataSquare([][]byte{{1, 2}, {3, 4}, {5, 6}, {7, 8}}, RSGF8)
+	if err != nil {
+		panic(err)
+	}
+	mapped, err := CreateMappedExtendedDataSquare(path, eds)
+	assert.NoError(t, err)
+	assert.NoError(t, mapped.Close())
+	valid, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"truncated header", valid[:headerSize-1]},
+		{"truncated chunks", valid[:headerSize+10]},
+		{"truncated roots", valid[:len(valid)-1]},
+		{"trailing bytes", append(append([]byte(nil), valid...), 0)},
+		{"roots", append(append([]byte(nil), valid[:len(valid)-1]...), valid[len(valid)-1]^1)},
+		{"serialized square", valid[:headerSize+16*2]},
+	}
+	for _, tt := range tests {
+		invalidPath := filepath.Join(dir, "invalid")
+		assert.NoError(t, ioutil.WriteFile(invalidPath, tt.data, 0644))
+		_, err := OpenMappedExtendedDataSquare(invalidPath)
+		assert.Error(t, err, tt.name)
+	}
+
+	_, err = OpenMappedExtendedDataSquare(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+
+	// The chunks are not checked on open, but when their rows and columns
+	// are proven.
+	corrupted := append([]byte(nil), valid...)
+	corrupted[headerSize] ^= 1
+	corruptedPath := filepath.Join(dir, "corrupted")
+	assert.NoError(t, ioutil.WriteFile(corruptedPath, corrupted, 0644))
+	mapped, err = OpenMappedExtendedDataSquare(corruptedPath)
+	assert.NoError(t, err)
+	defer mapped.Close()
+	assert.Equal(t, eds.RowRoots(), mapped.RowRoots())
+	_, err = mapped.ProveRowShare(0, 0)
+	assert.Equal(t, &RootMismatchError{RowAxis, 0}, err)
+	_, err = mapped.ProveColumnRange(0, 0, 2)
+	assert.Equal(t, &RootMismatchError{ColumnAxis, 0}, err)
+	proof, err := mapped.ProveRowShare(1, 0)
+	assert.NoError(t, err)
+	assert.True(t, VerifyShareProof(mapped.RowRoots()[1], proof, sha256.New))
+}
@@ -0,0 +1,349 @@
+package rsmt2d
+
+import (
+	"bytes"
+	"errors"
+	"hash"
+	"sync"
+)
+
+// Namespaced Merkle trees commit to leaves that are sorted by a namespace ID
+// prefix. Every node is prefixed with the minimum and maximum namespace of its
+// leaves, which allows proving that a set of leaves is exactly the set of
+// leaves of a namespace, or that a namespace is absent.
+//
+// In an extended data square the chunks of the original quadrant carry their
+// namespace as prefix. The parity chunks of the other quadrants are assigned
+// the reserved parity namespace, which is ignored when computing the maximum
+// namespace of a node, unless all of its leaves are parity.
+
+const (
+	nmtLeafPrefix = 0
+	nmtNodePrefix = 1
+)
+
+var _ Tree = &namespacedTree{}
+
+type namespacedTree struct {
+	namespaceSize int
+	hasher        hash.Hash
+	axisIndex     uint
+	leaves        [][]byte
+
+	mu     sync.Mutex
+	levels merkleLevels
+}
+
+// NewNamespacedTree returns a namespaced Merkle tree for the row or column at
+// axisIndex of an extended data square, with namespace IDs of namespaceSize
+// bytes. Chunks shorter than namespaceSize have their namespace padded with
+// zeros. The tree uses a hasher created by newHasher.
+func NewNamespacedTree(namespaceSize int, newHasher func() hash.Hash, axisIndex uint) Tree {
+	return &namespacedTree{
+		namespaceSize: namespaceSize,
+		hasher:        newHasher(),
+		axisIndex:     axisIndex,
+	}
+}
+
+// NamespacedTreeConstructor returns a TreeConstructorFn creating namespaced
+// Merkle trees, to be used with WithTree or SetTree.
+func NamespacedTreeConstructor(namespaceSize int, newHasher func() hash.Hash) TreeConstructorFn {
+	return func(axis Axis, index uint) Tree {
+		return NewNamespacedTree(namespaceSize, newHasher, index)
+	}
+}
+
+// ParityNamespace returns the namespace ID assigned to parity chunks.
+func ParityNamespace(namespaceSize int) []byte {
+	return bytes.Repeat([]byte{0xFF}, namespaceSize)
+}
+
+func (t *namespacedTree) Push(data []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.leaves = append(t.leaves, data)
+	t.levels = nil
+}
+
+func (t *namespacedTree) Root() []byte {
+	levels := t.computeLevels()
+	if len(levels) == 0 {
+		return nmtEmptyRoot(t.hasher, t.namespaceSize)
+	}
+	return levels.root()
+}
+
+// Prove returns a proof of inclusion of the leaf at index. The proof set
+// consists of the namespaced leaf, followed by the nodes of a NamespaceProof
+// of the range [index, index+1).
+func (t *namespacedTree) Prove(index uint) ([]byte, [][]byte, uint, error) {
+	if index >= uint(len(t.leaves)) {
+		return nil, nil, 0, errors.New("index out of range")
+	}
+	levels := t.computeLevels()
+	nodes := levels.rangeProofNodes(0, uint(len(t.leaves)), index, index+1, [][]byte{t.namespacedData(index)})
+	return levels.root(), nodes, uint(len(t.leaves)), nil
+}
+
+// namespacedData returns the leaf at index, prefixed by the parity namespace
+// if it is a parity chunk.
+func (t *namespacedTree) namespacedData(index uint) []byte {
+	originalWidth := uint(len(t.leaves)) / 2
+	if t.axisIndex >= originalWidth || index >= originalWidth {
+		return append(ParityNamespace(t.namespaceSize), t.leaves[index]...)
+	}
+
+	leaf := t.leaves[index]
+	if len(leaf) < t.namespaceSize {
+		padded := make([]byte, t.namespaceSize)
+		copy(padded, leaf)
+		return padded
+	}
+	return leaf
+}
+
+func (t *namespacedTree) computeLevels() merkleLevels {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.levels == nil {
+		leafHashes := make([][]byte, len(t.leaves))
+		for i := range t.leaves {
+			leafHashes[i] = nmtLeafHash(t.hasher, t.namespaceSize, t.namespacedData(uint(i)))
+		}
+		t.levels = newMerkleLevels(leafHashes, func(left, right []byte) []byte {
+			return nmtNodeHash(t.hasher, t.namespaceSize, left, right)
+		})
+	}
+	return t.levels
+}
+
+// proveNamespace returns the leaves of namespace nID and a proof that they
+// are all the leaves of nID in the tree.
+func (t *namespacedTree) proveNamespace(nID []byte) ([][]byte, *NamespaceProof, error) {
+	if len(nID) != t.namespaceSize {
+		return nil, nil, errors.New("invalid namespace ID size")
+	}
+
+	numLeaves := uint(len(t.leaves))
+	if numLeaves == 0 {
+		return nil, &NamespaceProof{}, nil
+	}
+	levels := t.computeLevels()
+	leafHashes := levels[0]
+	for i := 1; i < len(leafHashes); i++ {
+		if bytes.Compare(leafHashes[i-1][:t.namespaceSize], leafHashes[i][:t.namespaceSize]) > 0 {
+			return nil, nil, errors.New("leaves are not sorted by namespace")
+		}
+	}
+
+	root := levels.root()
+	minNs, maxNs := root[:t.namespaceSize], root[t.namespaceSize:2*t.namespaceSize]
+	if bytes.Compare(nID, minNs) < 0 || bytes.Compare(nID, maxNs) > 0 {
+		// The root alone proves the absence.
+		return nil, &NamespaceProof{NumLeaves: numLeaves}, nil
+	}
+
+	start := uint(0)
+	for start < numLeaves && bytes.Compare(leafHashes[start][:t.namespaceSize], nID) < 0 {
+		start++
+	}
+	end := start
+	for end < numLeaves && bytes.Equal(leafHashes[end][:t.namespaceSize], nID) {
+		end++
+	}
+
+	if start == end {
+		// Prove the first leaf with a greater namespace instead.
+		return nil, &NamespaceProof{
+			Start:     start,
+			End:       start + 1,
+			Nodes:     levels.rangeProofNodes(0, numLeaves, start, start+1, nil),
+			NumLeaves: numLeaves,
+			LeafHash:  leafHashes[start],
+		}, nil
+	}
+
+	leaves := make([][]byte, 0, end-start)
+	for i := start; i < end; i++ {
+		leaves = append(leaves, t.namespacedData(i))
+	}
+	return leaves, &NamespaceProof{
+		Start:     start,
+		End:       end,
+		Nodes:     levels.rangeProofNodes(0, numLeaves, start, end, nil),
+		NumLeaves: numLeaves,
+	}, nil
+}
+
+// NamespaceProof proves that a set of leaves are all the leaves of a
+// namespace in a namespaced Merkle tree, or that there are none.
+type NamespaceProof struct {
+	// Start and End delimit the proven range of leaves.
+	Start uint
+	End   uint
+	// Nodes are the namespaced hashes of the subtrees outside the proven
+	// range, from left to right.
+	Nodes [][]byte
+	// NumLeaves is the number of leaves in the tree.
+	NumLeaves uint
+	// LeafHash is set for proofs of absence. It is the hash of the leaf at
+	// Start, the first leaf with a namespace greater than the absent one.
+	LeafHash []byte
+}
+
+// IsOfAbsence reports whether the proof proves the absence of a namespace.
+func (p *NamespaceProof) IsOfAbsence() bool {
+	return len(p.LeafHash) > 0 || p.Start == p.End
+}
+
+// VerifyNamespace checks that leaves are all the leaves of namespace nID in
+// the namespaced Merkle tree with the given root. For proofs of absence,
+// leaves must be empty. The namespace size is taken from nID.
+func VerifyNamespace(newHasher func() hash.Hash, root []byte, nID []byte, leaves [][]byte, proof *NamespaceProof) bool {
+	namespaceSize := len(nID)
+	if proof == nil || len(root) < 2*namespaceSize {
+		return false
+	}
+	hasher := newHasher()
+
+	if proof.Start == proof.End {
+		// Absence proven by the namespace range of the root.
+		minNs, maxNs := root[:namespaceSize], root[namespaceSize:2*namespaceSize]
+		return len(leaves) == 0 && (bytes.Compare(nID, minNs) < 0 || bytes.Compare(nID, maxNs) > 0)
+	}
+	if proof.End <= proof.Start || proof.End > proof.NumLeaves || proof.NumLeaves > maxProofLeaves {
+		return false
+	}
+
+	var leafHashes [][]byte
+	if proof.IsOfAbsence() {
+		if len(leaves) != 0 || proof.End != proof.Start+1 || len(proof.LeafHash) < 2*namespaceSize {
+			return false
+		}
+		if bytes.Compare(proof.LeafHash[:namespaceSize], nID) <= 0 {
+			return false
+		}
+		leafHashes = [][]byte{proof.LeafHash}
+	} else {
+		if uint(len(leaves)) != proof.End-proof.Start {
+			return false
+		}
+		for _, leaf := range leaves {
+			if len(leaf) < namespaceSize || !bytes.Equal(leaf[:namespaceSize], nID) {
+				return false
+			}
+			leafHashes = append(leafHashes, nmtLeafHash(hasher, namespaceSize, leaf))
+		}
+	}
+
+	nodes := proof.Nodes
+	complete := true
+	var computeRoot func(start, end uint) []byte
+	computeRoot = func(start, end uint) []byte {
+		if end <= proof.Start || start >= proof.End {
+			if len(nodes) == 0 || len(nodes[0]) < 2*namespaceSize {
+				complete = false
+				return nil
+			}
+			node := nodes[0]
+			nodes = nodes[1:]
+			// Subtrees left of the range must only hold smaller namespaces,
+			// subtrees right of it only greater ones.
+			if end <= proof.Start && bytes.Compare(node[namespaceSize:2*namespaceSize], nID) >= 0 {
+				complete = false
+			}
+			if start >= proof.End && bytes.Compare(node[:namespaceSize], nID) <= 0 {
+				complete = false
+			}
+			return node
+		}
+		if end-start == 1 {
+			return leafHashes[start-proof.Start]
+		}
+		split := start + splitPoint(end-start)
+		left := computeRoot(start, split)
+		right := computeRoot(split, end)
+		if !complete {
+			return nil
+		}
+		return nmtNodeHash(hasher, namespaceSize, left, right)
+	}
+
+	computed := computeRoot(0, proof.NumLeaves)
+	return complete && len(nodes) == 0 && bytes.Equal(computed, root)
+}
+
+// ProveNamespace returns the chunks of namespace nID in the row or column at
+// index, prefixed by their namespace, with a proof against the corresponding
+// root. The square must use namespaced trees, see NamespacedTreeConstructor.
+func (eds *ExtendedDataSquare) ProveNamespace(axis Axis, index uint, nID []byte) ([][]byte, *NamespaceProof, error) {
+	if index >= eds.width {
+		return nil, nil, errors.New("index out of range")
+	}
+
+	cached, err := eds.tree(axis, index)
+	if err != nil {
+		return nil, nil, err
+	}
+	tree, ok := cached.(*namespacedTree)
+	if !ok {
+		return nil, nil, errors.New("square does not use namespaced trees")
+	}
+
+	return tree.proveNamespace(nID)
+}
+
+func nmtLeafHash(h hash.Hash, namespaceSize int, data []byte) []byte {
+	nID := data[:namespaceSize]
+	h.Reset()
+	h.Write([]byte{nmtLeafPrefix})
+	h.Write(data)
+
+	res := make([]byte, 0, 2*namespaceSize+h.Size())
+	res = append(res, nID...)
+	res = append(res, nID...)
+	return h.Sum(res)
+}
+
+func nmtNodeHash(h hash.Hash, namespaceSize int, left, right []byte) []byte {
+	parity := ParityNamespace(namespaceSize)
+	leftMin, leftMax := left[:namespaceSize], left[namespaceSize:2*namespaceSize]
+	rightMin, rightMax := right[:namespaceSize], right[namespaceSize:2*namespaceSize]
+
+	minNs := leftMin
+	if bytes.Compare(rightMin, leftMin) < 0 {
+		minNs = rightMin
+	}
+
+	// Ignore the maximum of subtrees that only hold parity leaves, unless
+	// both do.
+	var maxNs []byte
+	switch {
+	case bytes.Equal(leftMin, parity):
+		maxNs = rightMax
+	case bytes.Equal(rightMin, parity):
+		maxNs = leftMax
+	case bytes.Compare(leftMax, rightMax) > 0:
+		maxNs = leftMax
+	default:
+		maxNs = rightMax
+	}
+
+	h.Reset()
+	h.Write([]byte{nmtNodePrefix})
+	h.Write(left)
+	h.Write(right)
+
+	res := make([]byte, 0, 2*namespaceSize+h.Size())
+	res = append(res, minNs...)
+	res = append(res, maxNs...)
+	return h.Sum(res)
+}
+
+func nmtEmptyRoot(h hash.Hash, namespaceSize int) []byte {
+	h.Reset()
+	return h.Sum(make([]byte, 2*namespaceSize))
+}
@@ -0,0 +1,132 @@
+package rsmt2d
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testNamespaceSize = 8
+
+func namespace(n byte) []byte {
+	nID := make([]byte, testNamespaceSize)
+	nID[testNamespaceSize-1] = n
+	return nID
+}
+
+// namespacedSquare returns an extended data square of original width 4 whose
+// rows hold the namespaces 2, 2, 4, 6; 6, 6, 6, 8; ...
+func namespacedSquare(t *testing.T) *ExtendedDataSquare {
+	namespaces := []byte{2, 2, 4, 6, 6, 6, 6, 8, 10, 12, 12, 12, 14, 16, 18, 20}
+	chunks := make([][]byte, len(namespaces))
+	for i, n := range namespaces {
+		chunks[i] = append(namespace(n), bytes.Repeat([]byte{byte(i)}, 24)...)
+	}
+	eds, err := ComputeExtendedDataSquare(chunks, RSGF8, WithTree(NamespacedTreeConstructor(testNamespaceSize, sha256.New)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return eds
+}
+
+func TestNamespacedRoots(t *testing.T) {
+	eds := namespacedSquare(t)
+	parity := ParityNamespace(testNamespaceSize)
+
+	root := eds.RowRoots()[1]
+	assert.Equal(t, namespace(6), root[:testNamespaceSize])
+	assert.Equal(t, namespace(8), root[testNamespaceSize:2*testNamespaceSize], "parity namespace should be ignored")
+
+	root = eds.ColumnRoots()[0]
+	assert.Equal(t, namespace(2), root[:testNamespaceSize])
+	assert.Equal(t, namespace(14), root[testNamespaceSize:2*testNamespaceSize])
+
+	for _, root := range [][]byte{eds.RowRoots()[5], eds.ColumnRoots()[7]} {
+		assert.Equal(t, parity, root[:testNamespaceSize])
+		assert.Equal(t, parity, root[testNamespaceSize:2*testNamespaceSize])
+	}
+}
+
+func TestProveNamespace(t *testing.T) {
+	eds := namespacedSquare(t)
+	rowRoot := eds.RowRoots()[1]
+
+	// Inclusion.
+	leaves, proof, err := eds.ProveNamespace(RowAxis, 1, namespace(6))
+	assert.NoError(t, err)
+	assert.False(t, proof.IsOfAbsence())
+	assert.Equal(t, eds.Row(1)[:3], leaves)
+	assert.True(t, VerifyNamespace(sha256.New, rowRoot, namespace(6), leaves, proof))
+	assert.False(t, VerifyNamespace(sha256.New, rowRoot, namespace(6), leaves[:2], proof), "incomplete leaves must not verify")
+	assert.False(t, VerifyNamespace(sha256.New, eds.RowRoots()[2], namespace(6), leaves, proof))
+
+	leaves, proof, err = eds.ProveNamespace(ColumnAxis, 3, namespace(12))
+	assert.NoError(t, err)
+	assert.Len(t, leaves, 1)
+	assert.True(t, VerifyNamespace(sha256.New, eds.ColumnRoots()[3], namespace(12), leaves, proof))
+
+	// Absence within the namespace range of the root.
+	leaves, proof, err = eds.ProveNamespace(RowAxis, 0, namespace(3))
+	assert.NoError(t, err)
+	assert.True(t, proof.IsOfAbsence())
+	assert.Empty(t, leaves)
+	assert.True(t, VerifyNamespace(sha256.New, eds.RowRoots()[0], namespace(3), nil, proof))
+	assert.False(t, VerifyNamespace(sha256.New, eds.RowRoots()[0], namespace(4), nil, proof), "present namespace must not be proven absent")
+
+	// Absence outside of the namespace range of the root.
+	for _, n := range []byte{1, 9} {
+		leaves, proof, err = eds.ProveNamespace(RowAxis, 1, namespace(n))
+		assert.NoError(t, err)
+		assert.True(t, proof.IsOfAbsence())
+		assert.True(t, VerifyNamespace(sha256.New, rowRoot, namespace(n), leaves, proof))
+	}
+	assert.False(t, VerifyNamespace(sha256.New, rowRoot, namespace(6), nil, proof))
+	assert.False(t, VerifyNamespace(sha256.New, rowRoot, namespace(6), nil, nil), "a nil proof must not verify")
+	huge := &NamespaceProof{Start: 0, End: 1, NumLeaves: ^uint(0)>>1 + 6}
+	assert.False(t, VerifyNamespace(sha256.New, rowRoot, namespace(6), [][]byte{namespace(6)}, huge))
+
+	// Parity rows hold no namespace.
+	_, proof, err = eds.ProveNamespace(RowAxis, 6, namespace(6))
+	assert.NoError(t, err)
+	assert.True(t, proof.IsOfAbsence())
+	assert.True(t, VerifyNamespace(sha256.New, eds.RowRoots()[6], namespace(6), nil, proof))
+
+	_, _, err = eds.ProveNamespace(RowAxis, 1, []byte{6})
+	assert.Error(t, err, "namespace ID size must match")
+	_, _, err = eds.ProveNamespace(RowAxis, 8, namespace(6))
+	assert.Error(t, err, "index must be in range")
+
+	plain, err := ComputeExtendedDataSquare(eds.flattened()[:4], RSGF8)
+	assert.NoError(t, err)
+	_, _, err = plain.ProveNamespace(RowAxis, 0, namespace(2))
+	assert.Error(t, err, "square without namespaced trees cannot prove namespaces")
+}
+
+func TestNamespacedTreeProve(t *testing.T) {
+	eds := namespacedSquare(t)
+	root, proof, _, numLeaves, err := eds.computeRowProof(2, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, eds.RowRoots()[2], root)
+	assert.Equal(t, uint(8), numLeaves)
+	assert.Len(t, proof, 4)
+	assert.Equal(t, append(ParityNamespace(testNamespaceSize), eds.Cell(2, 5)...), proof[0])
+
+	_, err = eds.ProveRowShare(2, 5)
+	assert.Error(t, err, "namespaced squares cannot prove single shares")
+	_, err = eds.ProveColumnShare(2, 5)
+	assert.Error(t, err)
+}
+
+func TestRepairNamespacedSquare(t *testing.T) {
+	eds := namespacedSquare(t)
+	flattened := eds.flattened()
+	for _, i := range []int{0, 1, 2, 9, 18, 27, 36, 63} {
+		flattened[i] = nil
+	}
+	repaired, err := RepairExtendedDataSquare(eds.RowRoots(), eds.ColumnRoots(), flattened, RSGF8,
+		WithTree(NamespacedTreeConstructor(testNamespaceSize, sha256.New)))
+	assert.NoError(t, err)
+	assert.Equal(t, eds.flattened(), repaired.flattened())
+}
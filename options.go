@@ -0,0 +1,127 @@
+package rsmt2d
+
+import (
+	"crypto/sha256"
+	"hash"
+	"runtime"
+)
+
+// Option configures how an ExtendedDataSquare is computed.
+type Option func(*config)
+
+type config struct {
+	// parallelism is the number of goroutines used to encode the square and
+	// to compute its roots.
+	parallelism int
+	// newHasher creates a fresh hasher for every default tree.
+	newHasher func() hash.Hash
+	// newTree creates the row and column trees. If nil, the default tree
+	// is used.
+	newTree TreeConstructorFn
+	// validate makes imports check the encoding of the square.
+	validate bool
+	// expectedRowRoots and expectedColumnRoots, if set, are checked against
+	// the roots of imported squares.
+	expectedRowRoots    [][]byte
+	expectedColumnRoots [][]byte
+	// bufferPool, if set, provides the buffers backing squares.
+	bufferPool BufferPool
+}
+
+func newConfig(opts []Option) config {
+	cfg := config{
+		parallelism: runtime.GOMAXPROCS(0),
+		newHasher:   sha256.New,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// getBuffer returns a buffer of size bytes from the buffer pool, or a newly
+// allocated one. Buffers from the pool that are too small are dropped.
+func (cfg config) getBuffer(size int) []byte {
+	if cfg.bufferPool != nil {
+		if buf := cfg.bufferPool.Get(size); cap(buf) >= size {
+			return buf[:size]
+		}
+	}
+	return make([]byte, size)
+}
+
+// putBuffer returns a buffer taken by getBuffer to the buffer pool.
+func (cfg config) putBuffer(buf []byte) {
+	if cfg.bufferPool != nil {
+		cfg.bufferPool.Put(buf)
+	}
+}
+
+// WithParallelism sets the number of goroutines used to erasure code the
+// square and to compute its row and column roots. It defaults to GOMAXPROCS,
+// a value of 1 does all work sequentially.
+func WithParallelism(workers int) Option {
+	return func(cfg *config) {
+		if workers < 1 {
+			workers = 1
+		}
+		cfg.parallelism = workers
+	}
+}
+
+// WithHasher sets the constructor of the hasher used for the default row and
+// column trees. It defaults to sha256.New. The constructor is called once for
+// every tree, so it must return a new hasher on every call, and it is called
+// concurrently, so it must be safe for concurrent use.
+func WithHasher(newHasher func() hash.Hash) Option {
+	return func(cfg *config) {
+		cfg.newHasher = newHasher
+	}
+}
+
+// WithTree sets the constructor of the trees committing to the rows and
+// columns of the square. It defaults to NewDefaultTree. The constructor must
+// be safe for concurrent use, see TreeConstructorFn.
+func WithTree(newTree TreeConstructorFn) Option {
+	return func(cfg *config) {
+		cfg.newTree = newTree
+	}
+}
+
+// WithValidation makes ImportExtendedDataSquare and RepairExtendedDataSquare
+// check that the square is a valid extension of its original data, see
+// Validate.
+func WithValidation() Option {
+	return func(cfg *config) {
+		cfg.validate = true
+	}
+}
+
+// WithExpectedRoots makes ImportExtendedDataSquare and
+// RepairExtendedDataSquare check the roots of the square against the given
+// roots, see ValidateRoots.
+func WithExpectedRoots(rowRoots [][]byte, columnRoots [][]byte) Option {
+	return func(cfg *config) {
+		cfg.expectedRowRoots = rowRoots
+		cfg.expectedColumnRoots = columnRoots
+	}
+}
+
+// BufferPool provides the contiguous buffers backing squares, so that they can
+// be reused across squares of the same size. It must be safe for concurrent
+// use, as squares may be created and released from several goroutines.
+type BufferPool interface {
+	// Get returns a buffer of at least size bytes. Its content does not
+	// matter, it is overwritten.
+	Get(size int) []byte
+	// Put returns a buffer that is no longer used by any square.
+	Put(buf []byte)
+}
+
+// WithBufferPool makes squares take their buffers from pool. Buffers are
+// returned to the pool when a square is extended and by Release.
+func WithBufferPool(pool BufferPool) Option {
+	return func(cfg *config) {
+		cfg.bufferPool = pool
+	}
+}
@@ -0,0 +1,226 @@
+package rsmt2d
+
+import (
+	"bytes"
+	"errors"
+	"hash"
+
+	"github.com/NebulousLabs/merkletree"
+)
+
+// ShareProof is a Merkle inclusion proof of a share in the root of a row or
+// column.
+type ShareProof struct {
+	Share []byte
+	Axis  Axis
+	// AxisIndex is the index of the row or column the share is proven in.
+	AxisIndex uint
+	// Index is the index of the share within the row or column.
+	Index uint
+	// Siblings are the hashes of the sibling subtrees on the path from the
+	// share to the root, bottom up.
+	Siblings  [][]byte
+	NumLeaves uint
+}
+
+// ProveRowShare returns a proof of inclusion of the share at (row, column) in
+// the root of its row. Squares using namespaced trees prove their shares with
+// ProveNamespace instead.
+func (eds *ExtendedDataSquare) ProveRowShare(row uint, column uint) (*ShareProof, error) {
+	if row >= eds.width || column >= eds.width {
+		return nil, errors.New("index out of range")
+	}
+	if err := eds.checkShareProofs(RowAxis, row); err != nil {
+		return nil, err
+	}
+
+	_, proof, index, numLeaves, err := eds.computeRowProof(row, column)
+	if err != nil {
+		return nil, err
+	}
+	return newShareProof(eds.Cell(row, column), RowAxis, row, index, proof, numLeaves)
+}
+
+// ProveColumnShare returns a proof of inclusion of the share at
+// (row, column) in the root of its column. Squares using namespaced trees
+// prove their shares with ProveNamespace instead.
+func (eds *ExtendedDataSquare) ProveColumnShare(row uint, column uint) (*ShareProof, error) {
+	if row >= eds.width || column >= eds.width {
+		return nil, errors.New("index out of range")
+	}
+	if err := eds.checkShareProofs(ColumnAxis, column); err != nil {
+		return nil, err
+	}
+
+	_, proof, index, numLeaves, err := eds.computeColumnProof(row, column)
+	if err != nil {
+		return nil, err
+	}
+	return newShareProof(eds.Cell(row, column), ColumnAxis, column, index, proof, numLeaves)
+}
+
+// checkShareProofs returns an error if the tree of the row or column at index
+// proves shares in a format that VerifyShareProof cannot check.
+func (eds *ExtendedDataSquare) checkShareProofs(axis Axis, index uint) error {
+	tree, err := eds.tree(axis, index)
+	if err != nil {
+		return err
+	}
+	if _, ok := tree.(*namespacedTree); ok {
+		return errors.New("namespaced trees do not support share proofs, use ProveNamespace")
+	}
+	return nil
+}
+
+func newShareProof(share []byte, axis Axis, axisIndex uint, index uint, proofSet [][]byte, numLeaves uint) (*ShareProof, error) {
+	if len(proofSet) == 0 {
+		return nil, errors.New("tree returned an empty proof set")
+	}
+	return &ShareProof{
+		Share:     share,
+		Axis:      axis,
+		AxisIndex: axisIndex,
+		Index:     index,
+		// The first element of the proof set is the share itself.
+		Siblings:  proofSet[1:],
+		NumLeaves: numLeaves,
+	}, nil
+}
+
+// VerifyShareProof checks a proof of a share against the root of its row or
+// column, computed by the default tree with a hasher created by newHasher.
+func VerifyShareProof(root []byte, proof *ShareProof, newHasher func() hash.Hash) bool {
+	if proof == nil || proof.Index >= proof.NumLeaves {
+		return false
+	}
+
+	proofSet := make([][]byte, 0, len(proof.Siblings)+1)
+	proofSet = append(proofSet, proof.Share)
+	proofSet = append(proofSet, proof.Siblings...)
+	return merkletree.VerifyProof(newHasher(), root, proofSet, uint64(proof.Index), uint64(proof.NumLeaves))
+}
+
+// RangeProof is a Merkle proof of the contiguous shares [Start, End) of a row
+// or column.
+type RangeProof struct {
+	Shares [][]byte
+	Axis   Axis
+	// AxisIndex is the index of the row or column the shares are proven in.
+	AxisIndex uint
+	Start     uint
+	End       uint
+	// Nodes are the roots of the subtrees outside the proven range, from left
+	// to right.
+	Nodes     [][]byte
+	NumLeaves uint
+}
+
+// rangeProver is implemented by trees that can prove ranges of leaves.
+type rangeProver interface {
+	proveRange(start, end uint) (nodes [][]byte, numLeaves uint, err error)
+}
+
+func (t *defaultTree) proveRange(start, end uint) ([][]byte, uint, error) {
+	levels := t.computeLevels()
+	numLeaves := uint(len(t.leaves))
+	if start >= end || end > numLeaves {
+		return nil, 0, errors.New("invalid range")
+	}
+	return levels.rangeProofNodes(0, numLeaves, start, end, nil), numLeaves, nil
+}
+
+// ProveRowRange returns a proof of the shares [start, end) of a row against
+// the root of the row.
+func (eds *ExtendedDataSquare) ProveRowRange(row uint, start uint, end uint) (*RangeProof, error) {
+	if row >= eds.width {
+		return nil, errors.New("index out of range")
+	}
+	return eds.proveRange(RowAxis, row, start, end)
+}
+
+// ProveColumnRange returns a proof of the shares [start, end) of a column
+// against the root of the column.
+func (eds *ExtendedDataSquare) ProveColumnRange(column uint, start uint, end uint) (*RangeProof, error) {
+	if column >= eds.width {
+		return nil, errors.New("index out of range")
+	}
+	return eds.proveRange(ColumnAxis, column, start, end)
+}
+
+func (eds *ExtendedDataSquare) proveRange(axis Axis, index uint, start uint, end uint) (*RangeProof, error) {
+	if start >= end || end > eds.width {
+		return nil, errors.New("invalid range")
+	}
+
+	tree, err := eds.tree(axis, index)
+	if err != nil {
+		return nil, err
+	}
+	prover, ok := tree.(rangeProver)
+	if !ok {
+		return nil, errors.New("tree does not support range proofs")
+	}
+	nodes, numLeaves, err := prover.proveRange(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	shares := make([][]byte, 0, end-start)
+	for i := start; i < end; i++ {
+		if axis == RowAxis {
+			shares = append(shares, eds.Cell(index, i))
+		} else {
+			shares = append(shares, eds.Cell(i, index))
+		}
+	}
+
+	return &RangeProof{
+		Shares:    shares,
+		Axis:      axis,
+		AxisIndex: index,
+		Start:     start,
+		End:       end,
+		Nodes:     nodes,
+		NumLeaves: numLeaves,
+	}, nil
+}
+
+// VerifyRangeProof checks a proof of a range of shares against the root of
+// their row or column, computed by the default tree with a hasher created by
+// newHasher.
+func VerifyRangeProof(root []byte, proof *RangeProof, newHasher func() hash.Hash) bool {
+	if proof == nil || proof.Start >= proof.End || proof.End > proof.NumLeaves || proof.NumLeaves > maxProofLeaves ||
+		uint(len(proof.Shares)) != proof.End-proof.Start {
+		return false
+	}
+
+	hasher := newHasher()
+
+	nodes := proof.Nodes
+	valid := true
+	var computeRoot func(start, end uint) []byte
+	computeRoot = func(start, end uint) []byte {
+		if end <= proof.Start || start >= proof.End {
+			if len(nodes) == 0 {
+				valid = false
+				return nil
+			}
+			node := nodes[0]
+			nodes = nodes[1:]
+			return node
+		}
+		if end-start == 1 {
+			return hashWithPrefix(hasher, leafHashPrefix, proof.Shares[start-proof.Start])
+		}
+		split := start + splitPoint(end-start)
+		left := computeRoot(start, split)
+		right := computeRoot(split, end)
+		if !valid {
+			return nil
+		}
+		return hashWithPrefix(hasher, nodeHashPrefix, left, right)
+	}
+
+	computed := computeRoot(0, proof.NumLeaves)
+	return valid && len(nodes) == 0 && bytes.Equal(computed, root)
+}
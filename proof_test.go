@@ -0,0 +1,139 @@
+package rsmt2d
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShareProofs(t *testing.T) {
+	eds, err := ComputeExtendedDataSquare([][]byte{
+		{1, 2}, {3, 4}, {5, 6},
+		{7, 8}, {9, 10}, {11, 12},
+		{13, 14}, {15, 16}, {17, 18},
+	}, RSGF8)
+	if err != nil {
+		panic(err)
+	}
+
+	for row := uint(0); row < eds.Width(); row++ {
+		for column := uint(0); column < eds.Width(); column++ {
+			proof, err := eds.ProveRowShare(row, column)
+			assert.NoError(t, err)
+			assert.Equal(t, RowAxis, proof.Axis)
+			assert.Equal(t, row, proof.AxisIndex)
+			assert.Equal(t, column, proof.Index)
+			assert.Equal(t, eds.Cell(row, column), proof.Share)
+			assert.True(t, VerifyShareProof(eds.RowRoots()[row], proof, sha256.New))
+
+			proof, err = eds.ProveColumnShare(row, column)
+			assert.NoError(t, err)
+			assert.Equal(t, ColumnAxis, proof.Axis)
+			assert.Equal(t, column, proof.AxisIndex)
+			assert.Equal(t, row, proof.Index)
+			assert.True(t, VerifyShareProof(eds.ColumnRoots()[column], proof, sha256.New))
+		}
+	}
+
+	proof, err := eds.ProveRowShare(1, 2)
+	assert.NoError(t, err)
+	assert.False(t, VerifyShareProof(eds.RowRoots()[2], proof, sha256.New), "proof must not verify against another root")
+	assert.False(t, VerifyShareProof(eds.RowRoots()[1], proof, sha512.New), "proof must not verify with another hasher")
+
+	tampered := *proof
+	tampered.Share = []byte{0, 0}
+	assert.False(t, VerifyShareProof(eds.RowRoots()[1], &tampered, sha256.New))
+	tampered = *proof
+	tampered.Index = 3
+	assert.False(t, VerifyShareProof(eds.RowRoots()[1], &tampered, sha256.New))
+	assert.False(t, VerifyShareProof(eds.RowRoots()[1], nil, sha256.New))
+
+	_, err = eds.ProveRowShare(6, 0)
+	assert.Error(t, err)
+	_, err = eds.ProveColumnShare(0, 6)
+	assert.Error(t, err)
+}
+
+// emptyProofTree is a flatTree returning empty proof sets.
+type emptyProofTree struct {
+	flatTree
+}
+
+func (t *emptyProofTree) Prove(index uint) ([]byte, [][]byte, uint, error) {
+	return t.Root(), nil, uint(len(t.leaves)), nil
+}
+
+func TestShareProofEmptyProofSet(t *testing.T) {
+	eds, err := ComputeExtendedDataSquare([][]byte{{1}, {2}, {3}, {4}}, RSGF8, WithTree(func(axis Axis, index uint) Tree {
+		return &emptyProofTree{flatTree{axis: axis, index: index}}
+	}))
+	if err != nil {
+		panic(err)
+	}
+
+	_, err = eds.ProveRowShare(0, 0)
+	assert.Error(t, err)
+	_, err = eds.ProveColumnShare(0, 0)
+	assert.Error(t, err)
+}
+
+func TestRangeProofs(t *testing.T) {
+	eds, err := ComputeExtendedDataSquare([][]byte{
+		{1, 2}, {3, 4}, {5, 6},
+		{7, 8}, {9, 10}, {11, 12},
+		{13, 14}, {15, 16}, {17, 18},
+	}, RSGF8)
+	if err != nil {
+		panic(err)
+	}
+
+	for index := uint(0); index < eds.Width(); index++ {
+		for start := uint(0); start < eds.Width(); start++ {
+			for end := start + 1; end <= eds.Width(); end++ {
+				proof, err := eds.ProveRowRange(index, start, end)
+				assert.NoError(t, err)
+				assert.Equal(t, eds.Row(index)[start:end], proof.Shares)
+				assert.True(t, VerifyRangeProof(eds.RowRoots()[index], proof, sha256.New), "row %d [%d, %d)", index, start, end)
+
+				proof, err = eds.ProveColumnRange(index, start, end)
+				assert.NoError(t, err)
+				assert.Equal(t, eds.Column(index)[start:end], proof.Shares)
+				assert.True(t, VerifyRangeProof(eds.ColumnRoots()[index], proof, sha256.New), "column %d [%d, %d)", index, start, end)
+			}
+		}
+	}
+
+	proof, err := eds.ProveRowRange(2, 1, 5)
+	assert.NoError(t, err)
+	assert.Len(t, proof.Nodes, 2)
+	assert.False(t, VerifyRangeProof(eds.RowRoots()[3], proof, sha256.New))
+
+	tampered := *proof
+	tampered.Shares = append([][]byte{{0, 0}}, proof.Shares[1:]...)
+	assert.False(t, VerifyRangeProof(eds.RowRoots()[2], &tampered, sha256.New))
+	tampered = *proof
+	tampered.Shares = proof.Shares[1:]
+	assert.False(t, VerifyRangeProof(eds.RowRoots()[2], &tampered, sha256.New))
+	tampered = *proof
+	tampered.Start, tampered.End = 0, 4
+	assert.False(t, VerifyRangeProof(eds.RowRoots()[2], &tampered, sha256.New))
+	tampered = *proof
+	tampered.Nodes = proof.Nodes[:1]
+	assert.False(t, VerifyRangeProof(eds.RowRoots()[2], &tampered, sha256.New))
+	assert.False(t, VerifyRangeProof(eds.RowRoots()[2], nil, sha256.New))
+	tampered = *proof
+	tampered.NumLeaves = ^uint(0)>>1 + 6
+	assert.False(t, VerifyRangeProof(eds.RowRoots()[2], &tampered, sha256.New))
+
+	for _, r := range [][3]uint{{6, 0, 1}, {0, 2, 2}, {0, 3, 1}, {0, 0, 7}} {
+		_, err = eds.ProveRowRange(r[0], r[1], r[2])
+		assert.Error(t, err)
+	}
+
+	custom, err := ComputeExtendedDataSquare([][]byte{{1}, {2}, {3}, {4}}, RSGF8, WithTree(newFlatTree))
+	assert.NoError(t, err)
+	_, err = custom.ProveColumnRange(0, 0, 2)
+	assert.Error(t, err, "custom trees do not support range proofs")
+}
@@ -0,0 +1,296 @@
+package rsmt2d
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	gf16Order      = 1 << 16
+	gf16Modulus    = gf16Order - 1
+	gf16Polynomial = 0x1002D
+)
+
+// gf16LogZero stands for the logarithm of 0 in symbols converted by
+// gf16Logs. Adding the logarithm of any non-zero symbol to it indexes the
+// zeros at the end of gf16Exp.
+const gf16LogZero = 2 * gf16Modulus
+
+var (
+	gf16Exp [3 * gf16Modulus]uint16
+	gf16Log [gf16Order]uint16
+)
+
+var _ CachingCodec = &rsGF16Codec{}
+
+func init() {
+	x := 1
+	for i := 0; i < gf16Modulus; i++ {
+		gf16Exp[i] = uint16(x)
+		gf16Exp[i+gf16Modulus] = uint16(x)
+		gf16Log[x] = uint16(i)
+		x <<= 1
+		if x >= gf16Order {
+			x ^= gf16Polynomial
+		}
+	}
+
+	RegisterCodec(RSGF16, newRSGF16Codec())
+}
+
+// rsGF16Codec is a systematic Reed-Solomon code over GF(2^16). Chunks are
+// read as big-endian 16-bit symbols. The k data chunks are the evaluations
+// of a polynomial of degree < k at the points 0..k-1, and the parity chunks
+// are its evaluations at the points k..2k-1.
+type rsGF16Codec struct {
+	weightsCache *lruCache
+	// decodingWeightsCache holds the Lagrange weights of the points decoding
+	// interpolates from, keyed by gf16PointsKey.
+	decodingWeightsCache *lruCache
+}
+
+func newRSGF16Codec() *rsGF16Codec {
+	return &rsGF16Codec{
+		weightsCache: newLRUCache(DefaultCacheSize, func(k interface{}) (interface{}, error) {
+			if err := gf16CheckWidth(k.(int)); err != nil {
+				return nil, err
+			}
+			return lagrangeWeights(gf16Points(0, k.(int))), nil
+		}),
+		decodingWeightsCache: newLRUCache(DefaultCacheSize, func(key interface{}) (interface{}, error) {
+			return lagrangeWeights(gf16PointsOfKey(key.(string))), nil
+		}),
+	}
+}
+
+// encodingWeights returns the cached Lagrange weights of the points 0..k-1.
+// The returned slice must not be modified.
+func (c *rsGF16Codec) encodingWeights(k int) ([]uint16, error) {
+	weights, err := c.weightsCache.get(k)
+	if err != nil {
+		return nil, err
+	}
+	return weights.([]uint16), nil
+}
+
+func (c *rsGF16Codec) Encode(data [][]byte) ([][]byte, error) {
+	k := len(data)
+	if err := gf16CheckChunkSize(data); err != nil {
+		return nil, err
+	}
+
+	weights, err := c.encodingWeights(k)
+	if err != nil {
+		return nil, err
+	}
+	points := gf16Points(0, k)
+	logs := gf16Logs(data)
+
+	shares := make([][]byte, k)
+	sum := make([]uint16, len(data[0])/2)
+	for i := range shares {
+		shares[i] = make([]byte, len(data[0]))
+		interpolate(points, weights, logs, uint16(k+i), sum, shares[i])
+	}
+
+	return shares, nil
+}
+
+func (c *rsGF16Codec) Decode(data [][]byte) ([][]byte, error) {
+	k := len(data) / 2
+	if err := gf16CheckChunkSize(data); err != nil {
+		return nil, err
+	}
+	if len(data)%2 != 0 {
+		return nil, errors.New("number of shares must be even")
+	}
+	if err := gf16CheckWidth(k); err != nil {
+		return nil, err
+	}
+
+	points := make([]uint16, 0, k)
+	sources := make([][]byte, 0, k)
+	for i := 0; i < len(data) && len(points) < k; i++ {
+		if data[i] != nil {
+			points = append(points, uint16(i))
+			sources = append(sources, data[i])
+		}
+	}
+	if len(points) < k {
+		return nil, errors.New("not enough shares to rebuild data")
+	}
+
+	var weights []uint16
+	var logs [][]uint32
+	var sum []uint16
+	rebuiltShares := make([][]byte, k)
+	for i := 0; i < k; i++ {
+		rebuiltShares[i] = make([]byte, len(sources[0]))
+		if data[i] != nil {
+			copy(rebuiltShares[i], data[i])
+			continue
+		}
+
+		if weights == nil {
+			value, err := c.decodingWeightsCache.get(gf16PointsKey(points))
+			if err != nil {
+				return nil, err
+			}
+			weights = value.([]uint16)
+			logs = gf16Logs(sources)
+			sum = make([]uint16, len(sources[0])/2)
+		}
+		interpolate(points, weights, logs, uint16(i), sum, rebuiltShares[i])
+	}
+
+	return rebuiltShares, nil
+}
+
+func (c *rsGF16Codec) CodecType() CodecType {
+	return RSGF16
+}
+
+// MaxChunks returns the max. number of chunks each code supports in a 2D square.
+func (c *rsGF16Codec) MaxChunks() int {
+	return 32768 * 32768
+}
+
+// WarmCache computes the encoding weights for the given original data widths.
+func (c *rsGF16Codec) WarmCache(widths ...int) error {
+	return c.weightsCache.warm(widths...)
+}
+
+// SetCacheSize bounds the number of cached encoding weights, and separately
+// the number of cached decoding weights.
+func (c *rsGF16Codec) SetCacheSize(size int) {
+	c.weightsCache.setSize(size)
+	c.decodingWeightsCache.setSize(size)
+}
+
+// CacheStats returns the usage counters of the encoding weights cache.
+func (c *rsGF16Codec) CacheStats() CacheStats {
+	return c.weightsCache.cacheStats()
+}
+
+// gf16CheckWidth checks that k data chunks and their k parity chunks can be
+// told apart by 16-bit points.
+func gf16CheckWidth(k int) error {
+	if k <= 0 || k > gf16Order/2 {
+		return fmt.Errorf("number of data chunks must be between 1 and %d, got %d", gf16Order/2, k)
+	}
+	return nil
+}
+
+// gf16CheckChunkSize checks that all non-nil chunks have the same size, which
+// is a multiple of 2 bytes.
+func gf16CheckChunkSize(data [][]byte) error {
+	size := -1
+	for _, d := range data {
+		if d == nil {
+			continue
+		}
+		if len(d)%2 != 0 {
+			return errors.New("chunk size must be a multiple of 2 bytes")
+		}
+		if size != -1 && len(d) != size {
+			return errors.New("chunks must have the same size")
+		}
+		size = len(d)
+	}
+	return nil
+}
+
+func gf16Points(start, count int) []uint16 {
+	points := make([]uint16, count)
+	for i := range points {
+		points[i] = uint16(start + i)
+	}
+	return points
+}
+
+// gf16PointsKey encodes points as a string, to key caches by the erasure
+// pattern they were chosen from.
+func gf16PointsKey(points []uint16) string {
+	key := make([]byte, 2*len(points))
+	for i, x := range points {
+		key[2*i] = byte(x >> 8)
+		key[2*i+1] = byte(x)
+	}
+	return string(key)
+}
+
+func gf16PointsOfKey(key string) []uint16 {
+	points := make([]uint16, len(key)/2)
+	for i := range points {
+		points[i] = uint16(key[2*i])<<8 | uint16(key[2*i+1])
+	}
+	return points
+}
+
+// lagrangeWeights returns the barycentric weights 1 / prod_{j != i} (x_i - x_j)
+// of the given distinct points.
+func lagrangeWeights(points []uint16) []uint16 {
+	weights := make([]uint16, len(points))
+	for i, xi := range points {
+		logProd := 0
+		for j, xj := range points {
+			if i != j {
+				logProd = (logProd + int(gf16Log[xi^xj])) % gf16Modulus
+			}
+		}
+		weights[i] = gf16Exp[gf16Modulus-logProd]
+	}
+	return weights
+}
+
+// gf16Logs returns the logarithms of the symbols of chunks, with
+// gf16LogZero for zero symbols, so that they are looked up once per chunk
+// rather than once per product.
+func gf16Logs(chunks [][]byte) [][]uint32 {
+	logs := make([][]uint32, len(chunks))
+	for i, chunk := range chunks {
+		logs[i] = make([]uint32, len(chunk)/2)
+		for j := range logs[i] {
+			if s := uint16(chunk[2*j])<<8 | uint16(chunk[2*j+1]); s != 0 {
+				logs[i][j] = uint32(gf16Log[s])
+			} else {
+				logs[i][j] = gf16LogZero
+			}
+		}
+	}
+	return logs
+}
+
+// interpolate evaluates the polynomial through (points[i], values[i]) at x,
+// which must not be one of the points, and writes the result to out. The
+// values are given by their logarithms, see gf16Logs, and sum is scratch
+// space of a symbol per 2 bytes of out.
+func interpolate(points []uint16, weights []uint16, logValues [][]uint32, x uint16, sum []uint16, out []byte) {
+	// L(x) = prod_j (x - x_j)
+	logL := 0
+	for _, xj := range points {
+		logL = (logL + int(gf16Log[x^xj])) % gf16Modulus
+	}
+
+	for j := range sum {
+		sum[j] = 0
+	}
+	for i, xi := range points {
+		// c_i = L(x) * w_i / (x - x_i)
+		logC := (logL + int(gf16Log[weights[i]]) + gf16Modulus - int(gf16Log[x^xi])) % gf16Modulus
+		gf16MulAdd(sum, logValues[i], uint32(logC))
+	}
+
+	for j, p := range sum {
+		out[2*j] = byte(p >> 8)
+		out[2*j+1] = byte(p)
+	}
+}
+
+// gf16MulAdd sets sum ^= exp(logC) * exp(logIn), symbol by symbol.
+func gf16MulAdd(sum []uint16, logIn []uint32, logC uint32) {
+	exp := gf16Exp[logC : logC+gf16LogZero+1]
+	for j, l := range logIn {
+		sum[j] ^= exp[l]
+	}
+}
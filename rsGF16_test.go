@@ -0,0 +1,141 @@
+package rsmt2d
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestRSGF16Field(t *testing.T) {
+	seen := make(map[uint16]bool)
+	for i := 0; i < gf16Modulus; i++ {
+		x := gf16Exp[i]
+		if x == 0 || seen[x] {
+			t.Fatalf("polynomial %#x is not primitive", gf16Polynomial)
+		}
+		seen[x] = true
+		if int(gf16Log[x]) != i {
+			t.Fatalf("log(exp(%d)) = %d", i, gf16Log[x])
+		}
+	}
+}
+
+func TestRSGF16RoundTrip(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, count := range []int{1, 2, 3, 16, 300} {
+		data := make([][]byte, count)
+		for i := range data {
+			data[i] = make([]byte, 32)
+			rnd.Read(data[i])
+		}
+		parity, err := Encode(data, RSGF16)
+		if err != nil {
+			t.Fatalf("encoding %d chunks failed: %v", count, err)
+		}
+
+		shares := append(append([][]byte{}, data...), parity...)
+		for _, i := range rnd.Perm(2 * count)[:count] {
+			shares[i] = nil
+		}
+		rebuilt, err := Decode(shares, RSGF16)
+		if err != nil {
+			t.Fatalf("decoding %d chunks failed: %v", count, err)
+		}
+		for i := range data {
+			if !bytes.Equal(rebuilt[i], data[i]) {
+				t.Errorf("chunk %d of %d not rebuilt", i, count)
+			}
+		}
+
+		shares = append(append([][]byte{}, data...), parity...)
+		for _, i := range rnd.Perm(2 * count)[:count+1] {
+			shares[i] = nil
+		}
+		if _, err := Decode(shares, RSGF16); err == nil {
+			t.Errorf("decoding with too few chunks did not fail")
+		}
+	}
+
+	if _, err := Encode([][]byte{{1, 2, 3}}, RSGF16); err == nil {
+		t.Errorf("encoding chunks of odd size did not fail")
+	}
+}
+
+func TestRSGF16LargeSquare(t *testing.T) {
+	width := 256
+	data := make([][]byte, width*width)
+	for i := range data {
+		data[i] = []byte{byte(i >> 8), byte(i)}
+	}
+	if _, err := ComputeExtendedDataSquare(data, RSGF8); err == nil {
+		t.Errorf("RSGF8 accepted a %dx%d square", width, width)
+	}
+	eds, err := ComputeExtendedDataSquare(data, RSGF16)
+	if err != nil {
+		t.Fatalf("ComputeExtendedDataSquare failed for a %dx%d square: %v", width, width, err)
+	}
+
+	row := eds.Row(uint(width + 1))
+	shares := make([][]byte, len(row))
+	for i := range row {
+		if i%2 == 1 {
+			shares[i] = row[i]
+		}
+	}
+	rebuilt, err := Decode(shares, RSGF16)
+	if err != nil {
+		t.Fatalf("decoding an extended row failed: %v", err)
+	}
+	for i := 0; i < width; i++ {
+		if !bytes.Equal(rebuilt[i], row[i]) {
+			t.Errorf("chunk %d of extended row not rebuilt", i)
+		}
+	}
+}
+
+func TestRSGF16Decode(t *testing.T) {
+	codec := newRSGF16Codec()
+	data := [][]byte{{1, 2}, {3, 4}, {5, 6}}
+	parity, err := codec.Encode(data)
+	if err != nil {
+		t.Fatalf("encoding failed: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		rebuilt, err := codec.Decode([][]byte{nil, data[1], nil, parity[0], parity[1], nil})
+		if err != nil {
+			t.Fatalf("decoding failed: %v", err)
+		}
+		if !bytes.Equal(rebuilt[0], data[0]) || !bytes.Equal(rebuilt[2], data[2]) {
+			t.Errorf("chunks not rebuilt")
+		}
+	}
+	if stats := codec.decodingWeightsCache.cacheStats(); stats.Misses != 1 || stats.Hits != 1 {
+		t.Errorf("decoding weights of the same erasure pattern were not cached: %+v", stats)
+	}
+
+	if _, err := codec.Decode([][]byte{nil, data[1], {1, 2, 3, 4}, parity[0], nil, nil}); err == nil {
+		t.Errorf("decoding chunks of different sizes did not fail")
+	}
+	if _, err := codec.Encode([][]byte{{1, 2}, {3, 4, 5, 6}}); err == nil {
+		t.Errorf("encoding chunks of different sizes did not fail")
+	}
+}
+
+func TestRSGF16InvalidWidth(t *testing.T) {
+	codec := newRSGF16Codec()
+	for _, k := range []int{-1, 0, 32769} {
+		if err := codec.WarmCache(k); err == nil {
+			t.Errorf("warming the cache for %d chunks did not fail", k)
+		}
+	}
+	if _, err := codec.Encode(nil); err == nil {
+		t.Errorf("encoding no chunks did not fail")
+	}
+	if _, err := codec.Decode(make([][]byte, 2*32769)); err == nil {
+		t.Errorf("decoding %d chunks did not fail", 2*32769)
+	}
+	if stats := codec.CacheStats(); stats.Len != 0 {
+		t.Errorf("invalid widths were cached")
+	}
+}
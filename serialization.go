@@ -0,0 +1,260 @@
+package rsmt2d
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Serialized squares start with a header, followed by all chunks row by row:
+//
+//	magic      [4]byte  "RSMT"
+//	version    uint16
+//	codec      uint32
+//	width      uint32   width of the extended square
+//	chunkSize  uint32
+//	rootsHash  [32]byte SHA-256 of the row roots followed by the column roots
+//
+// All integers are big-endian.
+
+const (
+	serializationVersion = 1
+	headerSize           = 4 + 2 + 4 + 4 + 4 + sha256.Size
+)
+
+// MaxSerializedChunkSize and MaxSerializedSquareSize bound the chunk size and
+// the total size of all chunks of decoded squares, so that a header cannot
+// make a decoder allocate arbitrary amounts of memory.
+const (
+	MaxSerializedChunkSize  = 1 << 20
+	MaxSerializedSquareSize = 1 << 30
+)
+
+// readBlockSize is the amount of chunk data ReadFrom allocates before any of
+// it has arrived. The buffer then doubles as data is read.
+const readBlockSize = 1 << 20
+
+const maxInt = int(^uint(0) >> 1)
+
+var serializationMagic = [4]byte{'R', 'S', 'M', 'T'}
+
+type serializationHeader struct {
+	codec     CodecType
+	width     uint32
+	chunkSize uint32
+	rootsHash [sha256.Size]byte
+}
+
+func (h *serializationHeader) marshal() []byte {
+	buf := make([]byte, headerSize)
+	copy(buf, serializationMagic[:])
+	binary.BigEndian.PutUint16(buf[4:], serializationVersion)
+	binary.BigEndian.PutUint32(buf[6:], uint32(h.codec))
+	binary.BigEndian.PutUint32(buf[10:], h.width)
+	binary.BigEndian.PutUint32(buf[14:], h.chunkSize)
+	copy(buf[18:], h.rootsHash[:])
+	return buf
+}
+
+func (h *serializationHeader) unmarshal(buf []byte) error {
+	if !bytes.Equal(buf[:4], serializationMagic[:]) {
+		return errors.New("invalid magic")
+	}
+	if version := binary.BigEndian.Uint16(buf[4:]); version != serializationVersion {
+		return fmt.Errorf("unsupported format version %d", version)
+	}
+	h.codec = CodecType(binary.BigEndian.Uint32(buf[6:]))
+	h.width = binary.BigEndian.Uint32(buf[10:])
+	h.chunkSize = binary.BigEndian.Uint32(buf[14:])
+	copy(h.rootsHash[:], buf[18:])
+
+	codec, ok := GetCodec(h.codec)
+	if !ok {
+		return errors.New("unsupported codecType")
+	}
+	if h.width == 0 || h.width%2 != 0 {
+		return errors.New("square width must be even and non-zero")
+	}
+	if h.chunkSize == 0 {
+		return errors.New("chunk size must be non-zero")
+	}
+	if h.chunkSize > MaxSerializedChunkSize {
+		return fmt.Errorf("chunk size %d exceeds the maximum of %d", h.chunkSize, MaxSerializedChunkSize)
+	}
+	if uint64(h.width)*uint64(h.width) > 4*uint64(codec.MaxChunks()) {
+		return errors.New("number of chunks exceeds the maximum")
+	}
+	if uint64(h.width)*uint64(h.width)*uint64(h.chunkSize) > MaxSerializedSquareSize {
+		return fmt.Errorf("square size exceeds the maximum of %d bytes", MaxSerializedSquareSize)
+	}
+	return nil
+}
+
+// dataSize returns the size of all chunks following the header.
+func (h *serializationHeader) dataSize() int {
+	return int(h.width) * int(h.width) * int(h.chunkSize)
+}
+
+// MarshalBinary encodes the square, see WriteTo.
+func (eds *ExtendedDataSquare) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Grow(headerSize + len(eds.buf))
+	if _, err := eds.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteTo writes a versioned header, holding the codec, width, chunk size and
+// a hash of the roots, followed by all chunks of the square row by row.
+func (eds *ExtendedDataSquare) WriteTo(w io.Writer) (int64, error) {
+	header := serializationHeader{
+		codec:     eds.codec,
+		width:     uint32(eds.width),
+		chunkSize: uint32(eds.chunkSize),
+		rootsHash: rootsHash(eds.RowRoots(), eds.ColumnRoots()),
+	}
+
+	n, err := w.Write(header.marshal())
+	if err != nil {
+		return int64(n), err
+	}
+	m, err := w.Write(eds.buf)
+	return int64(n + m), err
+}
+
+// UnmarshalBinary decodes a square encoded by MarshalBinary. The data must
+// not hold anything after the square. Squares with chunks larger than
+// MaxSerializedChunkSize or more than MaxSerializedSquareSize bytes of chunks
+// are rejected. The roots are recomputed and checked against the header, with
+// the configuration of the receiver if it already holds a square, or with the
+// default configuration otherwise.
+func (eds *ExtendedDataSquare) UnmarshalBinary(data []byte) error {
+	if len(data) < headerSize {
+		return errors.New("data is truncated")
+	}
+	var header serializationHeader
+	if err := header.unmarshal(data[:headerSize]); err != nil {
+		return err
+	}
+
+	switch size := header.dataSize(); {
+	case len(data)-headerSize < size:
+		return errors.New("data is truncated")
+	case len(data)-headerSize > size:
+		return errors.New("data has trailing bytes")
+	}
+
+	cfg := eds.loadConfig()
+	buf := cfg.getBuffer(header.dataSize())
+	copy(buf, data[headerSize:])
+	return eds.load(&header, buf, cfg)
+}
+
+// ReadFrom reads a square written by WriteTo. See UnmarshalBinary for how the
+// roots are checked and which squares are rejected.
+func (eds *ExtendedDataSquare) ReadFrom(r io.Reader) (int64, error) {
+	headerBuf := make([]byte, headerSize)
+	n, err := io.ReadFull(r, headerBuf)
+	if err != nil {
+		return int64(n), truncated(err)
+	}
+	var header serializationHeader
+	if err := header.unmarshal(headerBuf); err != nil {
+		return int64(n), err
+	}
+
+	cfg := eds.loadConfig()
+	buf, m, err := readChunkData(r, header.dataSize(), cfg)
+	if err != nil {
+		return int64(n + m), truncated(err)
+	}
+	return int64(n + m), eds.load(&header, buf, cfg)
+}
+
+// readChunkData reads size bytes of chunks into a buffer taken from the buffer
+// pool of cfg. The buffer starts at readBlockSize and doubles as data arrives,
+// so that truncated input cannot claim more memory than twice its size.
+func readChunkData(r io.Reader, size int, cfg config) ([]byte, int, error) {
+	buf := cfg.getBuffer(minInt(size, readBlockSize))
+	n := 0
+	for {
+		m, err := io.ReadFull(r, buf[n:])
+		n += m
+		if err != nil {
+			cfg.putBuffer(buf)
+			return nil, n, err
+		}
+		if n == size {
+			return buf, n, nil
+		}
+
+		grown := cfg.getBuffer(minInt(2*len(buf), size))
+		copy(grown, buf)
+		cfg.putBuffer(buf)
+		buf = grown
+	}
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// loadConfig returns the configuration of the square, or the default one if
+// it does not hold a square yet.
+func (eds *ExtendedDataSquare) loadConfig() config {
+	if eds.dataSquare != nil {
+		return eds.config
+	}
+	return newConfig(nil)
+}
+
+// load replaces the square by the chunks in buf, taken from the buffer pool
+// of cfg, once they are checked against the roots hash of the header. The
+// buffer of the replaced square is returned to its pool.
+func (eds *ExtendedDataSquare) load(header *serializationHeader, buf []byte, cfg config) error {
+	ds := newDataSquareFromBuffer(buf, uint(header.width), uint(header.chunkSize), cfg)
+	ds.pooled = cfg.bufferPool != nil
+
+	if rootsHash(ds.RowRoots(), ds.ColumnRoots()) != header.rootsHash {
+		ds.release()
+		return errors.New("roots do not match the header")
+	}
+
+	if eds.dataSquare != nil {
+		eds.release()
+	}
+	eds.dataSquare = ds
+	eds.originalDataWidth = ds.width / 2
+	eds.codec = header.codec
+	return nil
+}
+
+func rootsHash(rowRoots [][]byte, columnRoots [][]byte) [sha256.Size]byte {
+	h := sha256.New()
+	for _, root := range rowRoots {
+		h.Write(root)
+	}
+	for _, root := range columnRoots {
+		h.Write(root)
+	}
+
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+// truncated turns an io.EOF in the middle of a square into
+// io.ErrUnexpectedEOF.
+func truncated(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
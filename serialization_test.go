@@ -0,0 +1,193 @@
+package rsmt2d
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarshalBinary(t *testing.T) {
+	for _, ct := range []CodecType{RSGF8, LeopardFF8, RSGF16} {
+		chunks := make([][]byte, 9)
+		for i := range chunks {
+			chunks[i] = bytes.Repeat([]byte{byte(i + 1)}, 64)
+		}
+		eds, err := ComputeExtendedDataSquare(chunks, ct)
+		if err != nil {
+			panic(err)
+		}
+
+		data, err := eds.MarshalBinary()
+		assert.NoError(t, err)
+		assert.Len(t, data, headerSize+36*64)
+
+		var loaded ExtendedDataSquare
+		assert.NoError(t, loaded.UnmarshalBinary(data))
+		assert.Equal(t, ct, loaded.codec)
+		assert.Equal(t, uint(3), loaded.originalDataWidth)
+		assert.Equal(t, eds.flattened(), loaded.flattened())
+		assert.Equal(t, eds.RowRoots(), loaded.RowRoots())
+		assert.Equal(t, eds.ColumnRoots(), loaded.ColumnRoots())
+
+		data[headerSize] ^= 1
+		assert.Equal(t, eds.flattened(), loaded.flattened(), "loaded square must not alias the input")
+	}
+}
+
+func TestWriteToReadFrom(t *testing.T) {
+	eds, err := ComputeExtendedDataSquare([][]byte{{1, 2}, {3, 4}, {5, 6}, {7, 8}}, RSGF8)
+	if err != nil {
+		panic(err)
+	}
+
+	var buf bytes.Buffer
+	n, err := eds.WriteTo(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(headerSize+16*2), n)
+	buf.WriteString("next square")
+
+	var loaded ExtendedDataSquare
+	n, err = loaded.ReadFrom(&buf)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(headerSize+16*2), n)
+	assert.Equal(t, eds.flattened(), loaded.flattened())
+	assert.Equal(t, "next square", buf.String(), "ReadFrom must not read past the square")
+
+	data, err := eds.MarshalBinary()
+	assert.NoError(t, err)
+	for _, size := range []int{0, 10, headerSize, len(data) - 1} {
+		_, err = loaded.ReadFrom(bytes.NewReader(data[:size]))
+		assert.Equal(t, io.ErrUnexpectedEOF, err, "size %d", size)
+		assert.Error(t, loaded.UnmarshalBinary(data[:size]), "size %d", size)
+	}
+}
+
+func TestUnmarshalBinaryInvalid(t *testing.T) {
+	eds, err := ComputeExtendedDataSquare([][]byte{{1, 2}, {3, 4}, {5, 6}, {7, 8}}, RSGF8)
+	if err != nil {
+		panic(err)
+	}
+	valid, err := eds.MarshalBinary()
+	if err != nil {
+		panic(err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(data []byte) []byte
+	}{
+		{"magic", func(data []byte) []byte { data[0] = 'X'; return data }},
+		{"version", func(data []byte) []byte { binary.BigEndian.PutUint16(data[4:], 2); return data }},
+		{"codec", func(data []byte) []byte { binary.BigEndian.PutUint32(data[6:], 12345); return data }},
+		{"odd width", func(data []byte) []byte { binary.BigEndian.PutUint32(data[10:], 3); return data }},
+		{"zero width", func(data []byte) []byte { binary.BigEndian.PutUint32(data[10:], 0); return data }},
+		{"huge width", func(data []byte) []byte { binary.BigEndian.PutUint32(data[10:], 1<<31); return data }},
+		{"zero chunk size", func(data []byte) []byte { binary.BigEndian.PutUint32(data[14:], 0); return data }},
+		{"chunk size", func(data []byte) []byte { binary.BigEndian.PutUint32(data[14:], 1); return data }},
+		{"roots hash", func(data []byte) []byte { data[18] ^= 1; return data }},
+		{"share", func(data []byte) []byte { data[len(data)-1] ^= 1; return data }},
+		{"trailing bytes", func(data []byte) []byte { return append(data, 0) }},
+	}
+	for _, tt := range tests {
+		data := tt.modify(append([]byte(nil), valid...))
+		var loaded ExtendedDataSquare
+		assert.Error(t, loaded.UnmarshalBinary(data), tt.name)
+		assert.Nil(t, loaded.dataSquare, tt.name)
+	}
+}
+
+func TestUnmarshalBinaryWithConfig(t *testing.T) {
+	chunks := [][]byte{{1, 2}, {3, 4}, {5, 6}, {7, 8}}
+	eds, err := ComputeExtendedDataSquare(chunks, RSGF8, WithHasher(sha512.New))
+	if err != nil {
+		panic(err)
+	}
+	data, err := eds.MarshalBinary()
+	assert.NoError(t, err)
+
+	var loaded ExtendedDataSquare
+	assert.Error(t, loaded.UnmarshalBinary(data), "roots of another hasher must not match")
+
+	receiver, err := ComputeExtendedDataSquare([][]byte{{0}}, RSGF8, WithHasher(sha512.New))
+	assert.NoError(t, err)
+	assert.NoError(t, receiver.UnmarshalBinary(data))
+	assert.Equal(t, eds.RowRoots(), receiver.RowRoots())
+}
+
+func TestUnmarshalBinaryReleasesBuffer(t *testing.T) {
+	eds, err := ComputeExtendedDataSquare([][]byte{{1, 2}, {3, 4}, {5, 6}, {7, 8}}, RSGF8)
+	if err != nil {
+		panic(err)
+	}
+	data, err := eds.MarshalBinary()
+	assert.NoError(t, err)
+
+	pool := newSizedPool()
+	receiver, err := ComputeExtendedDataSquare([][]byte{{1, 2}, {3, 4}, {5, 6}, {7, 8}}, RSGF8, WithBufferPool(pool))
+	if err != nil {
+		panic(err)
+	}
+	puts := pool.puts
+	assert.NoError(t, receiver.UnmarshalBinary(data))
+	assert.Equal(t, puts+1, pool.puts, "the replaced square must return its buffer")
+	_, err = receiver.ReadFrom(bytes.NewReader(data))
+	assert.NoError(t, err)
+	assert.Equal(t, puts+2, pool.puts)
+	assert.Equal(t, eds.flattened(), receiver.flattened())
+}
+
+// maxSizePool is a BufferPool recording the largest buffer requested.
+type maxSizePool struct {
+	maxSize int
+}
+
+func (p *maxSizePool) Get(size int) []byte {
+	if size > p.maxSize {
+		p.maxSize = size
+	}
+	return make([]byte, size)
+}
+
+func (p *maxSizePool) Put(buf []byte) {}
+
+func TestReadFromHugeHeader(t *testing.T) {
+	header := serializationHeader{codec: RSGF16, width: 1 << 15, chunkSize: 1 << 20}
+	var loaded ExtendedDataSquare
+	_, err := loaded.ReadFrom(bytes.NewReader(header.marshal()))
+	assert.Error(t, err)
+	assert.Error(t, loaded.UnmarshalBinary(header.marshal()))
+
+	header.chunkSize = 2 * MaxSerializedChunkSize
+	header.width = 2
+	_, err = loaded.ReadFrom(bytes.NewReader(header.marshal()))
+	assert.Error(t, err)
+
+	// The largest square accepted, of which only a few bytes arrive.
+	header = serializationHeader{codec: RSGF16, width: 1 << 14, chunkSize: 4}
+	eds, err := ComputeExtendedDataSquare([][]byte{{1, 2}}, RSGF8)
+	if err != nil {
+		panic(err)
+	}
+	pool := &maxSizePool{}
+	eds.config.bufferPool = pool
+	_, err = eds.ReadFrom(io.MultiReader(bytes.NewReader(header.marshal()), bytes.NewReader(make([]byte, 100))))
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+	assert.Equal(t, readBlockSize, pool.maxSize, "truncated input must not allocate the claimed size")
+	assert.Error(t, eds.UnmarshalBinary(append(header.marshal(), make([]byte, 100)...)))
+	assert.Equal(t, readBlockSize, pool.maxSize)
+}
+
+func TestReadChunkData(t *testing.T) {
+	data := make([]byte, 3*readBlockSize+5)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	buf, n, err := readChunkData(bytes.NewReader(data), len(data), newConfig(nil))
+	assert.NoError(t, err)
+	assert.Equal(t, len(data), n)
+	assert.Equal(t, data, buf)
+}
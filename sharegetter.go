@@ -0,0 +1,454 @@
+package rsmt2d
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrShareUnavailable is returned by a ShareGetter that does not have a share.
+var ErrShareUnavailable = errors.New("share unavailable")
+
+// ShareGetter provides the chunks of an extended data square on demand, for
+// instance from the network.
+type ShareGetter interface {
+	// GetShare returns the chunk at row and column. It may be called
+	// concurrently.
+	GetShare(ctx context.Context, row uint, column uint) ([]byte, error)
+}
+
+// RepairExtendedDataSquareWithGetter repairs an extended data square against
+// its expected row and column merkle roots, fetching chunks from getter as the
+// repair needs them. Whenever the square cannot be repaired any further, the
+// missing chunks of the row or column closest to being repairable are fetched,
+// just as many as it needs. Fetching stops once the square is complete.
+//
+// Chunks the getter fails to provide are treated as missing and other chunks
+// are tried instead, as are chunks whose size differs from that of most
+// fetched chunks. The fetched chunks of a row or column that turns out not
+// to match its root are dropped and not fetched again, unless they are part of
+// a row or column that matched its root.
+//
+// If the square cannot be repaired from all chunks the getter provides, the
+// partially repaired square is returned with an UnrepairableDataSquareError,
+// see RepairExtendedDataSquareWithResult. The partially repaired square is
+// also returned, with ctx.Err(), once ctx is done.
+func RepairExtendedDataSquareWithGetter(ctx context.Context, rowRoots [][]byte, columnRoots [][]byte, getter ShareGetter, codec CodecType, opts ...Option) (*RepairResult, error) {
+	width := uint(len(rowRoots))
+	if width == 0 || width%2 != 0 || uint(len(columnRoots)) != width {
+		return nil, errors.New("invalid number of roots")
+	}
+
+	r := &getterRepair{
+		getter:      getter,
+		rowRoots:    rowRoots,
+		columnRoots: columnRoots,
+		codec:       codec,
+		cfg:         newConfig(opts),
+		width:       width,
+		available:   NewAvailabilityMask(width),
+		fetched:     NewAvailabilityMask(width),
+		requested:   NewAvailabilityMask(width),
+		shares:      make([][]byte, width*width),
+		sizes:       make(map[int]uint),
+	}
+	for {
+		axis, index, ok := r.nextLine()
+		if r.lost {
+			// Stop early once the getter failed to provide too many chunks.
+			ok = ok && r.completable()
+			r.lost = false
+		}
+		if !ok {
+			return r.failed(ctx, newUnrepairableDataSquareError(r.available))
+		}
+		err := r.fetchLine(ctx, axis, index)
+		if err == nil && r.eds != nil {
+			err = r.eds.solveCrossword(ctx, rowRoots, columnRoots, r.available)
+		}
+
+		switch e := err.(type) {
+		case nil:
+			if r.eds == nil {
+				continue
+			}
+			if err := r.eds.validateConfigured(); err != nil {
+				return r.failed(ctx, err)
+			}
+			return &RepairResult{Square: r.eds, Reconstructed: r.available.without(r.fetched), Available: r.available}, nil
+		case *UnrepairableDataSquareError:
+			continue
+		case *ByzantineRowError:
+			if r.drop(RowAxis, e.RowNumber, &e.LastGoodSquare) {
+				continue
+			}
+		case *ByzantineColumnError:
+			if r.drop(ColumnAxis, e.ColumnNumber, &e.LastGoodSquare) {
+				continue
+			}
+		}
+		return r.failed(ctx, err)
+	}
+}
+
+// getterRepair is the state of a repair fetching chunks from a ShareGetter.
+type getterRepair struct {
+	getter      ShareGetter
+	rowRoots    [][]byte
+	columnRoots [][]byte
+	codec       CodecType
+	cfg         config
+	width       uint
+
+	// eds is the square being repaired, created once chunks were fetched.
+	// Its chunk size is that of most fetched chunks, until a row or column
+	// is complete and has been checked against its root.
+	eds *ExtendedDataSquare
+	// shares are the fetched chunks, including those whose size differs
+	// from the chunk size of eds, and sizes counts them by size.
+	shares [][]byte
+	sizes  map[int]uint
+	// available marks the known chunks of eds, fetched marks those that
+	// were fetched rather than reconstructed, and requested those that were
+	// requested from the getter, whether it provided them or not.
+	available *AvailabilityMask
+	fetched   *AvailabilityMask
+	requested *AvailabilityMask
+	// lost is set when requested chunks were not provided or dropped since
+	// the last check whether the square can still be completed.
+	lost bool
+}
+
+// cell returns the row and column of chunk j of the row or column at index.
+func (r *getterRepair) cell(axis Axis, index uint, j uint) (uint, uint) {
+	if axis == RowAxis {
+		return index, j
+	}
+	return j, index
+}
+
+// nextLine returns the incomplete row or column closest to being repairable,
+// among those with chunks that were not requested yet. Rows and columns that
+// can still become repairable come first, then those with the most available
+// chunks, then those with the fewest chunks the getter failed to provide.
+func (r *getterRepair) nextLine() (Axis, uint, bool) {
+	var (
+		bestAxis                   Axis
+		bestIndex                  uint
+		bestCount, bestFailures    uint
+		bestRepairable, bestExists bool
+	)
+	for _, axis := range []Axis{RowAxis, ColumnAxis} {
+		for index := uint(0); index < r.width; index++ {
+			var count, unrequested, failures uint
+			for j := uint(0); j < r.width; j++ {
+				x, y := r.cell(axis, index, j)
+				switch {
+				case r.available.IsSet(x, y):
+					count++
+				case r.requested.IsSet(x, y):
+					failures++
+				default:
+					unrequested++
+				}
+			}
+			if unrequested == 0 {
+				continue
+			}
+
+			repairable := count+unrequested >= r.width/2
+			better := !bestExists ||
+				repairable && !bestRepairable ||
+				repairable == bestRepairable && (count > bestCount || count == bestCount && failures < bestFailures)
+			if !better {
+				continue
+			}
+			bestAxis, bestIndex, bestExists = axis, index, true
+			bestCount, bestFailures, bestRepairable = count, failures, repairable
+		}
+	}
+	return bestAxis, bestIndex, bestExists
+}
+
+// fetchLine requests as many chunks of a row or column as it is missing to be
+// repairable, at least one, concurrently. Rows and columns completed by the
+// fetched chunks are checked against their roots.
+func (r *getterRepair) fetchLine(ctx context.Context, axis Axis, index uint) error {
+	var count uint
+	var cells [][2]uint
+	for j := uint(0); j < r.width; j++ {
+		x, y := r.cell(axis, index, j)
+		if r.available.IsSet(x, y) {
+			count++
+		} else if !r.requested.IsSet(x, y) {
+			cells = append(cells, [2]uint{x, y})
+		}
+	}
+	need := uint(1)
+	if count+1 < r.width/2 {
+		need = r.width/2 - count
+	}
+	if uint(len(cells)) > need {
+		cells = cells[:need]
+	}
+
+	shares := make([][]byte, len(cells))
+	ctxErr := parallelFor(r.cfg.parallelism, uint(len(cells)), func(i uint) error {
+		share, err := r.getter.GetShare(ctx, cells[i][0], cells[i][1])
+		if err == nil {
+			shares[i] = share
+		}
+		return ctx.Err()
+	})
+
+	for i, cell := range cells {
+		r.requested.Set(cell[0], cell[1])
+		if len(shares[i]) == 0 {
+			r.lost = true
+			continue
+		}
+		r.shares[cell[0]*r.width+cell[1]] = shares[i]
+		r.sizes[len(shares[i])]++
+	}
+
+	switch size := r.chunkSize(); {
+	case size == 0:
+		// No chunk was fetched yet.
+	case r.eds == nil || size != int(r.eds.chunkSize):
+		if err := r.rebuild(size); err != nil {
+			return err
+		}
+		// All fetched chunks may complete rows and columns now.
+		cells = cells[:0]
+		for x := uint(0); x < r.width; x++ {
+			for y := uint(0); y < r.width; y++ {
+				cells = append(cells, [2]uint{x, y})
+			}
+		}
+	default:
+		for _, cell := range cells {
+			r.apply(cell[0], cell[1])
+		}
+	}
+	if ctxErr != nil {
+		return ctxErr
+	}
+
+	for _, cell := range cells {
+		x, y := cell[0], cell[1]
+		if !r.fetched.IsSet(x, y) {
+			continue
+		}
+		if r.available.IsRowComplete(x) && !bytes.Equal(r.eds.RowRoots()[x], r.rowRoots[x]) {
+			edsBackup, _ := r.eds.deepCopy()
+			return &ByzantineRowError{x, edsBackup}
+		}
+		if r.available.IsColumnComplete(y) && !bytes.Equal(r.eds.ColumnRoots()[y], r.columnRoots[y]) {
+			edsBackup, _ := r.eds.deepCopy()
+			return &ByzantineColumnError{y, edsBackup}
+		}
+	}
+	return nil
+}
+
+// chunkSize returns the chunk size the square should have. Once a row or
+// column of eds is complete, its root has been checked, which confirms the
+// chunk size of eds. Until then, it is the size of most fetched chunks, so
+// that a few chunks of the wrong size cannot derail the repair.
+func (r *getterRepair) chunkSize() int {
+	if r.eds != nil {
+		for i := uint(0); i < r.width; i++ {
+			if r.available.IsRowComplete(i) || r.available.IsColumnComplete(i) {
+				return int(r.eds.chunkSize)
+			}
+		}
+	}
+
+	// Ties are broken in favour of the current size, then of larger sizes.
+	current := 0
+	if r.eds != nil {
+		current = int(r.eds.chunkSize)
+	}
+	best := current
+	for size, count := range r.sizes {
+		if count > r.sizes[best] || count == r.sizes[best] && best != current && size > best {
+			best = size
+		}
+	}
+	return best
+}
+
+// rebuild replaces eds by a square of chunks of size bytes, holding the
+// fetched chunks of that size.
+func (r *getterRepair) rebuild(size int) error {
+	fillerChunk := make([]byte, size)
+	filler := make([][]byte, r.width*r.width)
+	for i := range filler {
+		filler[i] = fillerChunk
+	}
+	eds, err := importExtendedDataSquare(filler, r.codec, r.cfg)
+	if err != nil {
+		return err
+	}
+
+	if r.eds != nil {
+		r.eds.release()
+	}
+	r.eds = eds
+	r.available = NewAvailabilityMask(r.width)
+	r.fetched = NewAvailabilityMask(r.width)
+	for x := uint(0); x < r.width; x++ {
+		for y := uint(0); y < r.width; y++ {
+			if r.shares[x*r.width+y] != nil {
+				r.apply(x, y)
+			}
+		}
+	}
+	return nil
+}
+
+// apply adds the fetched chunk of a cell to eds, unless it is missing or of
+// the wrong size.
+func (r *getterRepair) apply(x uint, y uint) {
+	share := r.shares[x*r.width+y]
+	if share == nil {
+		return
+	}
+	if r.eds.setCell(x, y, share) != nil {
+		// The chunk is of the wrong size.
+		r.lost = true
+		return
+	}
+	r.available.Set(x, y)
+	r.fetched.Set(x, y)
+}
+
+// drop restores the square to lastGood, the square before the repair of the
+// row or column failed, and drops the fetched chunks of the row or column that
+// are not part of a complete column or row, whose roots have been checked. It
+// reports whether any chunk was dropped.
+func (r *getterRepair) drop(axis Axis, index uint, lastGood *ExtendedDataSquare) bool {
+	if lastGood.dataSquare == nil {
+		// The solver failed to back up the square.
+		return false
+	}
+	copy(r.eds.buf, lastGood.buf)
+	r.eds.resetRoots()
+
+	dropped := false
+	missing := make([]byte, r.eds.chunkSize)
+	for j := uint(0); j < r.width; j++ {
+		x, y := r.cell(axis, index, j)
+		checked := r.available.IsColumnComplete(y)
+		if axis == ColumnAxis {
+			checked = r.available.IsRowComplete(x)
+		}
+		if r.fetched.IsSet(x, y) && !checked {
+			r.available.Clear(x, y)
+			r.fetched.Clear(x, y)
+			r.eds.setCell(x, y, missing)
+			r.sizes[len(r.shares[x*r.width+y])]--
+			r.shares[x*r.width+y] = nil
+			dropped = true
+		}
+	}
+	r.lost = r.lost || dropped
+	return dropped
+}
+
+// completable reports whether the square could be completed if all chunks
+// that were not requested yet are provided.
+func (r *getterRepair) completable() bool {
+	mask := r.available.Clone()
+	for x := uint(0); x < r.width; x++ {
+		for y := uint(0); y < r.width; y++ {
+			if !r.requested.IsSet(x, y) {
+				mask.Set(x, y)
+			}
+		}
+	}
+
+	for progress := true; progress; {
+		progress = false
+		for i := uint(0); i < r.width; i++ {
+			if !mask.IsRowComplete(i) && mask.RowCount(i) >= r.width/2 {
+				mask.SetRow(i)
+				progress = true
+			}
+			if !mask.IsColumnComplete(i) && mask.ColumnCount(i) >= r.width/2 {
+				mask.SetColumn(i)
+				progress = true
+			}
+		}
+	}
+	return mask.IsComplete()
+}
+
+// failed returns the result of a repair that failed with err, see
+// repairFailed.
+func (r *getterRepair) failed(ctx context.Context, err error) (*RepairResult, error) {
+	if r.eds != nil {
+		return r.eds.repairFailed(ctx, err, r.fetched, r.available)
+	}
+	if _, ok := err.(*UnrepairableDataSquareError); ok || err == ctx.Err() {
+		return &RepairResult{Reconstructed: NewAvailabilityMask(r.width), Available: r.available}, err
+	}
+	return nil, err
+}
+
+var _ ShareGetter = &MemoryShareGetter{}
+
+// MemoryShareGetter is a ShareGetter serving the chunks of a flattened
+// square from memory, meant for testing. Nil chunks are unavailable.
+type MemoryShareGetter struct {
+	// fetches is accessed atomically and first for 64-bit alignment.
+	fetches uint64
+
+	// Latency is the delay before every chunk is returned.
+	Latency time.Duration
+	// Fail, if set, is called on every request and makes it fail if it
+	// returns an error.
+	Fail func(row uint, column uint) error
+
+	width uint
+	data  [][]byte
+}
+
+// NewMemoryShareGetter returns a getter serving the chunks of a flattened
+// square of width*width chunks.
+func NewMemoryShareGetter(data [][]byte, width uint) *MemoryShareGetter {
+	return &MemoryShareGetter{width: width, data: data}
+}
+
+// GetShare returns a copy of the chunk at row and column after Latency, or
+// ErrShareUnavailable if it is nil or outside of the square.
+func (g *MemoryShareGetter) GetShare(ctx context.Context, row uint, column uint) ([]byte, error) {
+	atomic.AddUint64(&g.fetches, 1)
+
+	if g.Latency > 0 {
+		timer := time.NewTimer(g.Latency)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if g.Fail != nil {
+		if err := g.Fail(row, column); err != nil {
+			return nil, err
+		}
+	}
+	if row >= g.width || column >= g.width || g.data[row*g.width+column] == nil {
+		return nil, ErrShareUnavailable
+	}
+	return append([]byte(nil), g.data[row*g.width+column]...), nil
+}
+
+// Fetches returns the number of chunks requested so far.
+func (g *MemoryShareGetter) Fetches() uint {
+	return uint(atomic.LoadUint64(&g.fetches))
+}
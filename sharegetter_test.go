@@ -0,0 +1,165 @@
+package rsmt2d
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepairExtendedDataSquareWithGetter(t *testing.T) {
+	chunks := make([][]byte, 16)
+	for i := range chunks {
+		chunks[i] = bytes.Repeat([]byte{byte(i + 1)}, 64)
+	}
+	original, err := ComputeExtendedDataSquare(chunks, RSGF8)
+	if err != nil {
+		panic(err)
+	}
+
+	getter := NewMemoryShareGetter(original.flattened(), original.Width())
+	result, err := RepairExtendedDataSquareWithGetter(context.Background(), original.RowRoots(), original.ColumnRoots(), getter, RSGF8)
+	assert.NoError(t, err)
+	assert.Equal(t, original.flattened(), result.Square.flattened())
+	assert.Equal(t, uint(16), getter.Fetches(), "half the chunks of half the rows should be fetched")
+	assert.Equal(t, uint(48), result.Reconstructed.Count())
+
+	// Column 0 and row 1 cannot be fetched, so other chunks are tried.
+	getter = NewMemoryShareGetter(original.flattened(), original.Width())
+	getter.Fail = func(row uint, column uint) error {
+		if row == 1 || column == 0 {
+			return errors.New("unreachable")
+		}
+		return nil
+	}
+	result, err = RepairExtendedDataSquareWithGetter(context.Background(), original.RowRoots(), original.ColumnRoots(), getter, RSGF8, WithParallelism(4))
+	assert.NoError(t, err)
+	assert.Equal(t, original.flattened(), result.Square.flattened())
+	assert.Equal(t, uint(31), getter.Fetches())
+
+	// Only row 0 can be repaired. Fetching stops once too many chunks are
+	// unavailable for the square to be repaired.
+	data := make([][]byte, len(original.flattened()))
+	copy(data, original.flattened()[:4])
+	getter = NewMemoryShareGetter(data, original.Width())
+	result, err = RepairExtendedDataSquareWithGetter(context.Background(), original.RowRoots(), original.ColumnRoots(), getter, RSGF8)
+	assert.IsType(t, &UnrepairableDataSquareError{}, err)
+	if assert.NotNil(t, result) {
+		assert.Equal(t, original.Row(0), result.Square.Row(0))
+		assert.Equal(t, uint(8), result.Available.Count())
+	}
+	assert.Equal(t, uint(43), getter.Fetches())
+
+	_, err = RepairExtendedDataSquareWithGetter(context.Background(), original.RowRoots()[:7], original.ColumnRoots(), getter, RSGF8)
+	assert.Error(t, err)
+}
+
+func TestRepairExtendedDataSquareWithGetterContext(t *testing.T) {
+	original, err := ComputeExtendedDataSquare([][]byte{{1}, {2}, {3}, {4}}, RSGF8)
+	if err != nil {
+		panic(err)
+	}
+
+	getter := NewMemoryShareGetter(original.flattened(), original.Width())
+	getter.Latency = time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	result, err := RepairExtendedDataSquareWithGetter(ctx, original.RowRoots(), original.ColumnRoots(), getter, RSGF8)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	if assert.NotNil(t, result) {
+		assert.Nil(t, result.Square)
+		assert.Equal(t, uint(0), result.Available.Count())
+	}
+	assert.True(t, time.Since(start) < time.Second, "fetching should stop once ctx is done")
+}
+
+func TestRepairExtendedDataSquareWithGetterPartialContext(t *testing.T) {
+	chunks := make([][]byte, 16)
+	for i := range chunks {
+		chunks[i] = bytes.Repeat([]byte{byte(i + 1)}, 64)
+	}
+	original, err := ComputeExtendedDataSquare(chunks, RSGF8)
+	if err != nil {
+		panic(err)
+	}
+
+	// Row 0 is fetched and repaired before the getter blocks.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	getter := NewMemoryShareGetter(original.flattened(), original.Width())
+	getter.Fail = func(row uint, column uint) error {
+		if row != 0 {
+			cancel()
+			return ctx.Err()
+		}
+		return nil
+	}
+	result, err := RepairExtendedDataSquareWithGetter(ctx, original.RowRoots(), original.ColumnRoots(), getter, RSGF8, WithParallelism(1))
+	assert.Equal(t, context.Canceled, err)
+	if assert.NotNil(t, result) {
+		assert.Equal(t, original.Row(0), result.Square.Row(0))
+		assert.Equal(t, uint(8), result.Available.Count())
+		assert.Equal(t, uint(4), result.Reconstructed.Count())
+	}
+}
+
+func TestRepairExtendedDataSquareWithGetterByzantine(t *testing.T) {
+	chunks := make([][]byte, 16)
+	for i := range chunks {
+		chunks[i] = bytes.Repeat([]byte{byte(i + 1)}, 64)
+	}
+	original, err := ComputeExtendedDataSquare(chunks, RSGF8)
+	if err != nil {
+		panic(err)
+	}
+
+	// The chunks of row 0 are dropped once it does not match its root, and
+	// other chunks are fetched instead.
+	data := original.flattened()
+	data[1] = bytes.Repeat([]byte{0xFF}, 64)
+	data[2] = []byte{3}
+	getter := NewMemoryShareGetter(data, original.Width())
+	result, err := RepairExtendedDataSquareWithGetter(context.Background(), original.RowRoots(), original.ColumnRoots(), getter, RSGF8)
+	assert.NoError(t, err)
+	if assert.NotNil(t, result) {
+		assert.Equal(t, original.flattened(), result.Square.flattened())
+		assert.True(t, result.Available.IsComplete())
+		assert.True(t, result.Reconstructed.IsSet(0, 1))
+	}
+
+	// A chunk of the wrong size does not decide the chunk size, even if it
+	// is the first one fetched.
+	for _, parallelism := range []int{1, 4} {
+		data = original.flattened()
+		data[0] = []byte{1}
+		getter = NewMemoryShareGetter(data, original.Width())
+		result, err = RepairExtendedDataSquareWithGetter(context.Background(), original.RowRoots(), original.ColumnRoots(), getter, RSGF8, WithParallelism(parallelism))
+		assert.NoError(t, err)
+		if assert.NotNil(t, result) {
+			assert.Equal(t, original.flattened(), result.Square.flattened())
+		}
+		assert.Equal(t, uint(17), getter.Fetches())
+	}
+
+	// Chunks of the wrong size that agree on it are dropped once their rows
+	// do not match their roots.
+	data = original.flattened()
+	for i := 0; i < 3; i++ {
+		data[i] = []byte{1}
+	}
+	getter = NewMemoryShareGetter(data, original.Width())
+	result, err = RepairExtendedDataSquareWithGetter(context.Background(), original.RowRoots(), original.ColumnRoots(), getter, RSGF8)
+	assert.NoError(t, err)
+	if assert.NotNil(t, result) {
+		assert.Equal(t, original.flattened(), result.Square.flattened())
+	}
+
+	// Squares that match none of the roots cannot be repaired.
+	_, err = RepairExtendedDataSquareWithGetter(context.Background(), original.ColumnRoots(), original.RowRoots(), NewMemoryShareGetter(original.flattened(), original.Width()), RSGF8)
+	assert.Error(t, err)
+}
@@ -0,0 +1,51 @@
+// +build ignore
+
+// This program generates the digests of TestLeopardKnownAnswers with
+// github.com/klauspost/reedsolomon v1.11.8, an independent implementation of
+// the Leopard-RS codes, so that they do not depend on the cgo toolchain:
+//
+//	go mod init leopardvectors
+//	go get github.com/klauspost/reedsolomon@v1.11.8
+//	go run main.go
+//
+// Like leo_encode, it uses the 8-bit field up to 128 data chunks, otherwise
+// the 16-bit field.
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/klauspost/reedsolomon"
+)
+
+func main() {
+	for _, n := range []int{4, 128, 200} {
+		size := 64
+		shards := make([][]byte, 2*n)
+		for i := range shards {
+			shards[i] = make([]byte, size)
+			if i < n {
+				for j := range shards[i] {
+					shards[i][j] = byte(i*size + j)
+				}
+			}
+		}
+		opt := reedsolomon.WithLeopardGF(true)
+		if n > 128 {
+			opt = reedsolomon.WithLeopardGF16(true)
+		}
+		enc, err := reedsolomon.New(n, n, opt)
+		if err != nil {
+			panic(err)
+		}
+		if err := enc.Encode(shards); err != nil {
+			panic(err)
+		}
+		h := sha256.New()
+		for _, s := range shards[n:] {
+			h.Write(s)
+		}
+		fmt.Printf("%d %x\n", n, h.Sum(nil))
+	}
+}
@@ -0,0 +1,189 @@
+package rsmt2d
+
+import (
+	"errors"
+	"hash"
+	"math/bits"
+	"sync"
+)
+
+// Axis identifies whether a tree commits to a row or a column of a square.
+type Axis int
+
+const (
+	RowAxis Axis = iota
+	ColumnAxis
+)
+
+// Tree is a Merkle tree committing to the chunks of a single row or column.
+type Tree interface {
+	// Push appends a leaf to the tree.
+	Push(data []byte)
+	// Root returns the Merkle root of all leaves pushed so far.
+	Root() []byte
+	// Prove returns the Merkle root, the proof set and the number of leaves
+	// for an inclusion proof of the leaf at index. The first element of the
+	// proof set is the leaf itself. Once all leaves are pushed, Root and Prove
+	// may be called concurrently.
+	Prove(index uint) (merkleRoot []byte, proofSet [][]byte, numLeaves uint, err error)
+}
+
+// TreeConstructorFn creates an empty tree for the row or column at index. It
+// is called concurrently when roots are computed in parallel, so it must be
+// safe for concurrent use.
+type TreeConstructorFn func(axis Axis, index uint) Tree
+
+var _ Tree = &defaultTree{}
+
+// defaultTree is a binary Merkle tree compatible with
+// github.com/NebulousLabs/merkletree. It keeps all its nodes once computed, so
+// that proofs do not rehash the leaves.
+type defaultTree struct {
+	hasher hash.Hash
+	leaves [][]byte
+
+	mu     sync.Mutex
+	levels merkleLevels
+}
+
+// NewDefaultTree returns the tree used unless a TreeConstructorFn is
+// configured, a github.com/NebulousLabs/merkletree compatible tree using a
+// hasher created by newHasher.
+func NewDefaultTree(newHasher func() hash.Hash) Tree {
+	return &defaultTree{hasher: newHasher()}
+}
+
+func (t *defaultTree) Push(data []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.leaves = append(t.leaves, data)
+	t.levels = nil
+}
+
+func (t *defaultTree) Root() []byte {
+	return t.computeLevels().root()
+}
+
+func (t *defaultTree) Prove(index uint) ([]byte, [][]byte, uint, error) {
+	levels := t.computeLevels()
+	if index >= uint(len(t.leaves)) {
+		return nil, nil, 0, errors.New("index out of range")
+	}
+
+	proofSet := append([][]byte{t.leaves[index]}, levels.siblings(index)...)
+	return levels.root(), proofSet, uint(len(t.leaves)), nil
+}
+
+func (t *defaultTree) computeLevels() merkleLevels {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.levels == nil {
+		leafHashes := make([][]byte, len(t.leaves))
+		for i, leaf := range t.leaves {
+			leafHashes[i] = hashWithPrefix(t.hasher, leafHashPrefix, leaf)
+		}
+		t.levels = newMerkleLevels(leafHashes, func(left, right []byte) []byte {
+			return hashWithPrefix(t.hasher, nodeHashPrefix, left, right)
+		})
+	}
+	return t.levels
+}
+
+const (
+	leafHashPrefix = 0
+	nodeHashPrefix = 1
+)
+
+func hashWithPrefix(h hash.Hash, prefix byte, data ...[]byte) []byte {
+	h.Reset()
+	h.Write([]byte{prefix})
+	for _, d := range data {
+		h.Write(d)
+	}
+	return h.Sum(nil)
+}
+
+// merkleLevels holds all nodes of a binary Merkle tree whose left subtrees
+// hold the largest power of two of leaves smaller than the size of their
+// parent. levels[0] are the leaf hashes and the last level is the root. A
+// node without a sibling is carried up to the next level unchanged.
+type merkleLevels [][][]byte
+
+func newMerkleLevels(leafHashes [][]byte, hashNode func(left, right []byte) []byte) merkleLevels {
+	if len(leafHashes) == 0 {
+		return merkleLevels{}
+	}
+
+	levels := merkleLevels{leafHashes}
+	for level := leafHashes; len(level) > 1; {
+		next := make([][]byte, (len(level)+1)/2)
+		for i := range next {
+			if 2*i+1 < len(level) {
+				next[i] = hashNode(level[2*i], level[2*i+1])
+			} else {
+				next[i] = level[2*i]
+			}
+		}
+		levels = append(levels, next)
+		level = next
+	}
+	return levels
+}
+
+// root returns the root of the tree, or nil if it has no leaves.
+func (l merkleLevels) root() []byte {
+	if len(l) == 0 {
+		return nil
+	}
+	return l[len(l)-1][0]
+}
+
+// node returns the root of the subtree over the leaves [start, end), which
+// must be a subtree of the tree.
+func (l merkleLevels) node(start, end uint) []byte {
+	level := uint(0)
+	for uint(1)<<level < end-start {
+		level++
+	}
+	return l[level][start>>level]
+}
+
+// rangeProofNodes appends the roots of all maximal subtrees of [start, end)
+// that do not overlap [proofStart, proofEnd), from left to right.
+func (l merkleLevels) rangeProofNodes(start, end, proofStart, proofEnd uint, nodes [][]byte) [][]byte {
+	if end <= proofStart || start >= proofEnd {
+		return append(nodes, l.node(start, end))
+	}
+	if end-start == 1 {
+		return nodes
+	}
+	split := start + splitPoint(end-start)
+	nodes = l.rangeProofNodes(start, split, proofStart, proofEnd, nodes)
+	return l.rangeProofNodes(split, end, proofStart, proofEnd, nodes)
+}
+
+// splitPoint returns the size of the left subtree of a tree with n > 1
+// leaves: the largest power of two smaller than n.
+func splitPoint(n uint) uint {
+	return 1 << (bits.Len(n-1) - 1)
+}
+
+// maxProofLeaves bounds the number of leaves of the trees proofs are verified
+// against, far above the width of any square, so that untrusted proofs cannot
+// claim trees too large to recompute the root of.
+const maxProofLeaves = 1 << 31
+
+// siblings returns the hashes of the siblings on the path from the leaf at
+// index to the root, bottom up.
+func (l merkleLevels) siblings(index uint) [][]byte {
+	var siblings [][]byte
+	for _, level := range l[:len(l)-1] {
+		if sibling := index ^ 1; sibling < uint(len(level)) {
+			siblings = append(siblings, level[sibling])
+		}
+		index >>= 1
+	}
+	return siblings
+}
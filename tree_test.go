@@ -0,0 +1,165 @@
+package rsmt2d
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/NebulousLabs/merkletree"
+	"github.com/stretchr/testify/assert"
+)
+
+// flatTree commits to its leaves by hashing them together with the axis and
+// index it was created for. Proofs consist of the proven leaf followed by all
+// leaves.
+type flatTree struct {
+	axis   Axis
+	index  uint
+	leaves [][]byte
+}
+
+func (t *flatTree) Push(data []byte) {
+	t.leaves = append(t.leaves, data)
+}
+
+func (t *flatTree) Root() []byte {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d/%d", t.axis, t.index)
+	for _, leaf := range t.leaves {
+		h.Write(leaf)
+	}
+	return h.Sum(nil)
+}
+
+func (t *flatTree) Prove(index uint) ([]byte, [][]byte, uint, error) {
+	if index >= uint(len(t.leaves)) {
+		return nil, nil, 0, fmt.Errorf("index %d out of range", index)
+	}
+	return t.Root(), append([][]byte{t.leaves[index]}, t.leaves...), uint(len(t.leaves)), nil
+}
+
+func newFlatTree(axis Axis, index uint) Tree {
+	return &flatTree{axis: axis, index: index}
+}
+
+func TestDefaultTree(t *testing.T) {
+	for n := 1; n <= 33; n++ {
+		tree := NewDefaultTree(sha256.New)
+		expected := merkletree.New(sha256.New())
+		for i := 0; i < n; i++ {
+			tree.Push([]byte{byte(i)})
+			expected.Push([]byte{byte(i)})
+		}
+		if !bytes.Equal(tree.Root(), expected.Root()) {
+			t.Errorf("default tree root does not match merkletree root for %d leaves", n)
+		}
+
+		for i := 0; i < n; i++ {
+			expected := merkletree.New(sha256.New())
+			_ = expected.SetIndex(uint64(i))
+			for j := 0; j < n; j++ {
+				expected.Push([]byte{byte(j)})
+			}
+			_, expectedProof, _, _ := expected.Prove()
+
+			root, proof, numLeaves, err := tree.Prove(uint(i))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assert.Equal(t, expectedProof, proof, "proof of leaf %d of %d", i, n)
+			if !merkletree.VerifyProof(sha256.New(), root, proof, uint64(i), uint64(numLeaves)) {
+				t.Errorf("default tree proof of leaf %d of %d does not verify", i, n)
+			}
+		}
+	}
+
+	tree := NewDefaultTree(sha256.New)
+	assert.Nil(t, tree.Root())
+	_, _, _, err := tree.Prove(0)
+	assert.Error(t, err)
+}
+
+func TestSplitPoint(t *testing.T) {
+	for n, expected := range map[uint]uint{2: 1, 3: 2, 4: 2, 5: 4, 8: 4, 9: 8} {
+		assert.Equal(t, expected, splitPoint(n), "n = %d", n)
+	}
+	highBit := ^uint(0)>>1 + 1
+	assert.Equal(t, highBit, splitPoint(highBit+5))
+	assert.Equal(t, highBit>>1, splitPoint(highBit))
+}
+
+func TestCachedTrees(t *testing.T) {
+	trees := 0
+	newTree := func(axis Axis, index uint) Tree {
+		trees++
+		return NewDefaultTree(sha256.New)
+	}
+	eds, err := ComputeExtendedDataSquare([][]byte{{1}, {2}, {3}, {4}}, RSGF8, WithTree(newTree), WithParallelism(1))
+	assert.NoError(t, err)
+
+	for i := uint(0); i < eds.Width(); i++ {
+		_, err := eds.ProveRowShare(i, 0)
+		assert.NoError(t, err)
+		_, err = eds.ProveColumnShare(0, i)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 8, trees, "proofs should be served from the trees of computeRoots")
+
+	eds.setCell(1, 2, []byte{42})
+	assert.Nil(t, eds.rowTrees[1])
+	assert.Nil(t, eds.columnTrees[2])
+	assert.NotNil(t, eds.rowTrees[0])
+	assert.NotNil(t, eds.columnTrees[1])
+
+	proof, err := eds.ProveRowShare(1, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{42}, proof.Share)
+	assert.True(t, VerifyShareProof(eds.RowRoots()[1], proof, sha256.New))
+	proof, err = eds.ProveColumnShare(1, 2)
+	assert.NoError(t, err)
+	assert.True(t, VerifyShareProof(eds.ColumnRoots()[2], proof, sha256.New))
+}
+
+func TestCustomTree(t *testing.T) {
+	chunks := [][]byte{
+		bytes.Repeat([]byte{1}, 64), bytes.Repeat([]byte{2}, 64),
+		bytes.Repeat([]byte{3}, 64), bytes.Repeat([]byte{4}, 64),
+	}
+	original, err := ComputeExtendedDataSquare(chunks, RSGF8, WithTree(newFlatTree))
+	if err != nil {
+		panic(err)
+	}
+
+	for i := uint(0); i < original.Width(); i++ {
+		tree := newFlatTree(RowAxis, i)
+		for _, chunk := range original.Row(i) {
+			tree.Push(chunk)
+		}
+		if !bytes.Equal(original.RowRoots()[i], tree.Root()) {
+			t.Errorf("row root %d was not computed with the custom tree", i)
+		}
+	}
+
+	root, proof, _, numLeaves, err := original.computeColumnProof(1, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(root, original.ColumnRoots()[2]) || len(proof) != 5 || numLeaves != 4 {
+		t.Errorf("column proof was not computed with the custom tree")
+	}
+
+	flattened := original.flattened()
+	flattened[0], flattened[1], flattened[5] = nil, nil, nil
+	_, err = RepairExtendedDataSquare(original.RowRoots(), original.ColumnRoots(), flattened, RSGF8, WithTree(newFlatTree))
+	if err != nil {
+		t.Errorf("unexpected err while repairing data square with custom tree: %v", err)
+	}
+
+	flattened = original.flattened()
+	flattened[0], flattened[1], flattened[5] = nil, nil, nil
+	_, err = RepairExtendedDataSquare(original.RowRoots(), original.ColumnRoots(), flattened, RSGF8)
+	if err == nil {
+		t.Errorf("repairing against roots of a different tree did not fail")
+	}
+}
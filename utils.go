@@ -1,10 +1,67 @@
 package rsmt2d
 
+import "sync"
+
 func flattenChunks(chunks [][]byte) []byte {
-	flattened := chunks[0]
-	for _, chunk := range chunks[1:] {
+	// Always allocate, appending to chunks[0] could overwrite whatever
+	// follows it in its backing array.
+	flattened := make([]byte, 0, len(chunks)*len(chunks[0]))
+	for _, chunk := range chunks {
 		flattened = append(flattened, chunk...)
 	}
 
 	return flattened
 }
+
+// parallelFor calls fn for every index in [0, n) using up to workers
+// goroutines. It stops handing out indices after the first error, which it
+// returns.
+func parallelFor(workers int, n uint, fn func(i uint) error) error {
+	if workers <= 1 || n <= 1 {
+		for i := uint(0); i < n; i++ {
+			if err := fn(i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if uint(workers) > n {
+		workers = int(n)
+	}
+
+	var (
+		mu       sync.Mutex
+		next     uint
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	claim := func() (uint, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		if firstErr != nil || next >= n {
+			return 0, false
+		}
+		next++
+		return next - 1, true
+	}
+
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i, ok := claim(); ok; i, ok = claim() {
+				if err := fn(i); err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	return firstErr
+}
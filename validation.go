@@ -0,0 +1,118 @@
+package rsmt2d
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// InvalidEncodingError is returned when the parity chunks of a row or column
+// are not the Reed-Solomon encoding of its first half.
+type InvalidEncodingError struct {
+	Axis  Axis
+	Index uint
+}
+
+func (e *InvalidEncodingError) Error() string {
+	return fmt.Sprintf("invalid %s encoding: %d", axisName(e.Axis), e.Index)
+}
+
+// RootMismatchError is returned when the root of a row or column does not
+// match the expected root.
+type RootMismatchError struct {
+	Axis  Axis
+	Index uint
+}
+
+func (e *RootMismatchError) Error() string {
+	return fmt.Sprintf("%s root mismatch: %d", axisName(e.Axis), e.Index)
+}
+
+func axisName(axis Axis) string {
+	if axis == ColumnAxis {
+		return "column"
+	}
+	return "row"
+}
+
+// Validate re-encodes the first half of every row and column and compares
+// the result to its second half. It returns an InvalidEncodingError naming
+// the first inconsistent row or, if all rows are consistent, column.
+func (eds *ExtendedDataSquare) Validate() error {
+	invalidRows := make([]bool, eds.width)
+	invalidColumns := make([]bool, eds.width)
+	err := parallelFor(eds.config.parallelism, 2*eds.width, func(i uint) error {
+		var data [][]byte
+		invalid := invalidRows
+		if i < eds.width {
+			data = eds.Row(i)
+		} else {
+			i -= eds.width
+			data = eds.Column(i)
+			invalid = invalidColumns
+		}
+
+		parity, err := Encode(data[:eds.originalDataWidth], eds.codec)
+		if err != nil {
+			return err
+		}
+		for j, chunk := range parity {
+			if !bytes.Equal(chunk, data[eds.originalDataWidth+uint(j)]) {
+				invalid[i] = true
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for i, invalid := range invalidRows {
+		if invalid {
+			return &InvalidEncodingError{Axis: RowAxis, Index: uint(i)}
+		}
+	}
+	for i, invalid := range invalidColumns {
+		if invalid {
+			return &InvalidEncodingError{Axis: ColumnAxis, Index: uint(i)}
+		}
+	}
+	return nil
+}
+
+// ValidateRoots compares the roots of the square to the expected roots. It
+// returns a RootMismatchError naming the first mismatching row or, if all
+// rows match, column.
+func (eds *ExtendedDataSquare) ValidateRoots(rowRoots [][]byte, columnRoots [][]byte) error {
+	if uint(len(rowRoots)) != eds.width || uint(len(columnRoots)) != eds.width {
+		return errors.New("number of expected roots does not match square width")
+	}
+
+	actualRowRoots, actualColumnRoots := eds.RowRoots(), eds.ColumnRoots()
+	for i := range rowRoots {
+		if !bytes.Equal(rowRoots[i], actualRowRoots[i]) {
+			return &RootMismatchError{Axis: RowAxis, Index: uint(i)}
+		}
+	}
+	for i := range columnRoots {
+		if !bytes.Equal(columnRoots[i], actualColumnRoots[i]) {
+			return &RootMismatchError{Axis: ColumnAxis, Index: uint(i)}
+		}
+	}
+	return nil
+}
+
+// validateConfigured runs the validations enabled by WithValidation and
+// WithExpectedRoots.
+func (eds *ExtendedDataSquare) validateConfigured() error {
+	if eds.config.validate {
+		if err := eds.Validate(); err != nil {
+			return err
+		}
+	}
+	if eds.config.expectedRowRoots != nil || eds.config.expectedColumnRoots != nil {
+		return eds.ValidateRoots(eds.config.expectedRowRoots, eds.config.expectedColumnRoots)
+	}
+	return nil
+}
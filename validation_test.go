@@ -0,0 +1,75 @@
+package rsmt2d
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func validationTestSquare(t *testing.T, codec CodecType) *ExtendedDataSquare {
+	chunks := make([][]byte, 9)
+	for i := range chunks {
+		chunks[i] = bytes.Repeat([]byte{byte(i + 1)}, 64)
+	}
+	eds, err := ComputeExtendedDataSquare(chunks, codec)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return eds
+}
+
+func TestValidate(t *testing.T) {
+	for _, codec := range []CodecType{RSGF8, LeopardFF8, LeopardFF16, RSGF16} {
+		eds := validationTestSquare(t, codec)
+		assert.NoError(t, eds.Validate(), "codec %d", codec)
+
+		tampered := eds.Cell(1, 4)
+		tampered[0] ^= 1
+		eds.setCell(1, 4, tampered)
+		assert.Equal(t, &InvalidEncodingError{Axis: RowAxis, Index: 1}, eds.Validate(), "codec %d", codec)
+	}
+
+	// Replace the fourth row by a valid encoding of other data, so that only
+	// the columns are inconsistent.
+	eds := validationTestSquare(t, RSGF8)
+	row := [][]byte{bytes.Repeat([]byte{7}, 64), bytes.Repeat([]byte{8}, 64), bytes.Repeat([]byte{9}, 64)}
+	parity, err := Encode(row, RSGF8)
+	assert.NoError(t, err)
+	assert.NoError(t, eds.setRowSlice(3, 0, append(row, parity...)))
+	assert.Equal(t, &InvalidEncodingError{Axis: ColumnAxis, Index: 0}, eds.Validate())
+}
+
+func TestValidateRoots(t *testing.T) {
+	eds := validationTestSquare(t, RSGF8)
+	rowRoots, columnRoots := eds.RowRoots(), eds.ColumnRoots()
+	assert.NoError(t, eds.ValidateRoots(rowRoots, columnRoots))
+
+	swapped := append([][]byte(nil), columnRoots...)
+	swapped[2], swapped[3] = swapped[3], swapped[2]
+	assert.Equal(t, &RootMismatchError{Axis: ColumnAxis, Index: 2}, eds.ValidateRoots(rowRoots, swapped))
+	assert.Equal(t, &RootMismatchError{Axis: RowAxis, Index: 0}, eds.ValidateRoots(swapped, swapped))
+	assert.Error(t, eds.ValidateRoots(rowRoots[:5], columnRoots))
+}
+
+func TestImportWithValidation(t *testing.T) {
+	eds := validationTestSquare(t, RSGF8)
+	rowRoots, columnRoots := eds.RowRoots(), eds.ColumnRoots()
+
+	_, err := ImportExtendedDataSquare(eds.flattened(), RSGF8, WithValidation(), WithExpectedRoots(rowRoots, columnRoots))
+	assert.NoError(t, err)
+
+	flattened := eds.flattened()
+	flattened[35] = bytes.Repeat([]byte{0xAB}, 64)
+	_, err = ImportExtendedDataSquare(flattened, RSGF8)
+	assert.NoError(t, err, "imports are not validated by default")
+	_, err = ImportExtendedDataSquare(flattened, RSGF8, WithValidation())
+	assert.Equal(t, &InvalidEncodingError{Axis: RowAxis, Index: 5}, err)
+	_, err = ImportExtendedDataSquare(flattened, RSGF8, WithExpectedRoots(rowRoots, columnRoots))
+	assert.Equal(t, &RootMismatchError{Axis: RowAxis, Index: 5}, err)
+
+	flattened = eds.flattened()
+	flattened[0], flattened[7] = nil, nil
+	_, err = RepairExtendedDataSquare(rowRoots, columnRoots, flattened, RSGF8, WithValidation(), WithExpectedRoots(rowRoots, columnRoots))
+	assert.NoError(t, err, "repair must validate the repaired square")
+}